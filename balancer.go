@@ -0,0 +1,250 @@
+package srpc
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Balancer picks the address for every call among the addresses produced by
+// the [Resolver] of the [Client]. Balancer must be safe for concurrent use.
+type Balancer interface {
+	// Update replaces the set of addresses to pick from. It is called every
+	// time the resolver produces a new set.
+	Update(addrs []string)
+
+	// Pick returns the address to send req to. done is called once the
+	// call is finished. Pick returns [ErrNoAddrs] if there are no addresses.
+	Pick(ctx context.Context, req Request) (addr string, done func(), err error)
+}
+
+func noop() {}
+
+// addrSet is the set of addresses, shared by balancers.
+type addrSet struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (s *addrSet) Update(addrs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addrs = slices.Clone(addrs)
+}
+
+type roundRobin struct {
+	addrSet
+	next int
+}
+
+// NewRoundRobinBalancer returns the balancer, which picks the addresses in
+// turn. It is the default balancer of the [Client].
+func NewRoundRobinBalancer() Balancer {
+	return &roundRobin{}
+}
+
+func (b *roundRobin) Pick(ctx context.Context, req Request) (string, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.addrs) == 0 {
+		return "", nil, ErrNoAddrs
+	}
+
+	b.next = (b.next + 1) % len(b.addrs)
+	return b.addrs[b.next], noop, nil
+}
+
+type random struct {
+	addrSet
+}
+
+// NewRandomBalancer returns the balancer, which picks random addresses.
+func NewRandomBalancer() Balancer {
+	return &random{}
+}
+
+func (b *random) Pick(ctx context.Context, req Request) (string, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.addrs) == 0 {
+		return "", nil, ErrNoAddrs
+	}
+
+	return b.addrs[rand.IntN(len(b.addrs))], noop, nil
+}
+
+// outstanding counts the calls in progress per address.
+type outstanding struct {
+	addrSet
+	calls map[string]int
+}
+
+// start counts new call to addr, returning the func to finish it. It must be
+// called with mu held.
+func (o *outstanding) start(addr string) func() {
+	if o.calls == nil {
+		o.calls = make(map[string]int)
+	}
+	o.calls[addr]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			o.mu.Lock()
+			defer o.mu.Unlock()
+
+			o.calls[addr]--
+			if o.calls[addr] <= 0 {
+				delete(o.calls, addr)
+			}
+		})
+	}
+}
+
+type leastOutstanding struct {
+	outstanding
+	next int
+}
+
+// NewLeastOutstandingBalancer returns the balancer, which picks the address
+// with the least number of calls in progress. Ties are broken in turn.
+func NewLeastOutstandingBalancer() Balancer {
+	return &leastOutstanding{}
+}
+
+func (b *leastOutstanding) Pick(ctx context.Context, req Request) (string, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.addrs) == 0 {
+		return "", nil, ErrNoAddrs
+	}
+
+	b.next = (b.next + 1) % len(b.addrs)
+	best := b.addrs[b.next]
+	for i := range b.addrs {
+		addr := b.addrs[(b.next+i)%len(b.addrs)]
+		if b.calls[addr] < b.calls[best] {
+			best = addr
+		}
+	}
+
+	return best, b.start(best), nil
+}
+
+type p2c struct {
+	outstanding
+}
+
+// NewP2CBalancer returns the power of two choices balancer: it picks two
+// random addresses and takes the one with less calls in progress.
+func NewP2CBalancer() Balancer {
+	return &p2c{}
+}
+
+func (b *p2c) Pick(ctx context.Context, req Request) (string, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.addrs) == 0 {
+		return "", nil, ErrNoAddrs
+	}
+
+	addr := b.addrs[rand.IntN(len(b.addrs))]
+	if len(b.addrs) > 1 {
+		i, j := rand.IntN(len(b.addrs)), rand.IntN(len(b.addrs)-1)
+		if j >= i {
+			j++
+		}
+		addr = b.addrs[i]
+		if b.calls[b.addrs[j]] < b.calls[addr] {
+			addr = b.addrs[j]
+		}
+	}
+
+	return addr, b.start(addr), nil
+}
+
+// _hashReplicas is the number of points of every address on the hash ring.
+const _hashReplicas = 100
+
+type consistentHash struct {
+	key string
+
+	mu     sync.Mutex
+	ring   []uint64 // sorted points
+	owners map[uint64]string
+	addrs  []string
+}
+
+// NewConsistentHashBalancer returns the balancer, which picks the address
+// by the hash of the metadata value with key, so calls with the same value
+// go to the same address, as long as it is resolved. Adding or removing an
+// address moves only a fraction of the values. Calls without the key go to
+// random addresses.
+func NewConsistentHashBalancer(key string) Balancer {
+	return &consistentHash{key: key}
+}
+
+func (b *consistentHash) Update(addrs []string) {
+	ring := make([]uint64, 0, len(addrs)*_hashReplicas)
+	owners := make(map[uint64]string, len(addrs)*_hashReplicas)
+	for _, addr := range addrs {
+		for i := range _hashReplicas {
+			h := hashString(fmt.Sprintf("%s#%d", addr, i))
+			ring = append(ring, h)
+			owners[h] = addr
+		}
+	}
+	slices.Sort(ring)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.ring, b.owners, b.addrs = ring, owners, slices.Clone(addrs)
+}
+
+func (b *consistentHash) Pick(ctx context.Context, req Request) (string, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.addrs) == 0 {
+		return "", nil, ErrNoAddrs
+	}
+
+	vals := req.Metadata.Get(b.key)
+	if len(vals) == 0 {
+		return b.addrs[rand.IntN(len(b.addrs))], noop, nil
+	}
+
+	h := hashString(vals[0])
+	i := sort.Search(len(b.ring), func(i int) bool { return b.ring[i] >= h })
+	if i == len(b.ring) {
+		i = 0
+	}
+
+	return b.owners[b.ring[i]], noop, nil
+}
+
+// hashString returns the hash of s. FNV of similar strings is not spread
+// evenly, so it is finalized with the mixer of splitmix64.
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
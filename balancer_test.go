@@ -0,0 +1,188 @@
+package srpc_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/resolver"
+	"github.com/tymbaca/srpc/transport/inmem"
+)
+
+// startReplicas starts n replicas named r0, r1, ..., which reply after delay.
+func startReplicas(t *testing.T, n int, delay time.Duration) (*inmem.Cluster, []string) {
+	cluster := inmem.New()
+
+	var addrs []string
+	for i := range n {
+		addr := srpctest.Start(t, cluster, newReplica(fmt.Sprintf("r%d", i), delay), "Replica").Addr
+		addrs = append(addrs, addr)
+	}
+
+	return cluster, addrs
+}
+
+// callReplicas makes n concurrent calls and returns the number of calls
+// handled by every replica.
+func callReplicas(t *testing.T, ctx context.Context, c *srpc.Client, n int) map[string]int {
+	var mu sync.Mutex
+	counts := map[string]int{}
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var resp string
+			if err := c.Call(ctx, "Replica.Get", "", &resp); err != nil {
+				t.Error(err)
+				return
+			}
+
+			mu.Lock()
+			counts[resp]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return counts
+}
+
+func TestBalancers(t *testing.T) {
+	ctx := t.Context()
+
+	newClient := func(t *testing.T, cluster *inmem.Cluster, addrs []string, b srpc.Balancer) *srpc.Client {
+		c := srpc.NewClient("", codec.JSON, cluster.NewPeer(), srpc.WithResolver(resolver.Static(addrs)), srpc.WithBalancer(b))
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+
+	t.Run("round robin", func(t *testing.T) {
+		cluster, addrs := startReplicas(t, 3, 0)
+		c := newClient(t, cluster, addrs, srpc.NewRoundRobinBalancer())
+
+		counts := map[string]int{}
+		for range 6 {
+			var resp string
+			require.NoError(t, c.Call(ctx, "Replica.Get", "", &resp))
+			counts[resp]++
+		}
+		require.Equal(t, map[string]int{"r0": 2, "r1": 2, "r2": 2}, counts)
+	})
+
+	t.Run("random", func(t *testing.T) {
+		cluster, addrs := startReplicas(t, 3, 0)
+		c := newClient(t, cluster, addrs, srpc.NewRandomBalancer())
+
+		counts := callReplicas(t, ctx, c, 60)
+		require.Len(t, counts, 3)
+	})
+
+	t.Run("least outstanding", func(t *testing.T) {
+		// calls overlap, so each of them goes to the idle replica
+		cluster, addrs := startReplicas(t, 3, 50*time.Millisecond)
+		c := newClient(t, cluster, addrs, srpc.NewLeastOutstandingBalancer())
+
+		require.Equal(t, map[string]int{"r0": 1, "r1": 1, "r2": 1}, callReplicas(t, ctx, c, 3))
+		require.Equal(t, map[string]int{"r0": 2, "r1": 2, "r2": 2}, callReplicas(t, ctx, c, 6))
+	})
+
+	t.Run("power of two choices", func(t *testing.T) {
+		cluster, addrs := startReplicas(t, 2, 50*time.Millisecond)
+		c := newClient(t, cluster, addrs, srpc.NewP2CBalancer())
+
+		require.Equal(t, map[string]int{"r0": 1, "r1": 1}, callReplicas(t, ctx, c, 2))
+	})
+
+	t.Run("consistent hash", func(t *testing.T) {
+		cluster, addrs := startReplicas(t, 5, 0)
+		c := newClient(t, cluster, addrs, srpc.NewConsistentHashBalancer("user-id"))
+
+		replicas := map[string]bool{}
+		for i := range 20 {
+			userCtx := srpc.AppendToOutgoingContext(ctx, "user-id", fmt.Sprint(i))
+
+			var first string
+			for range 3 {
+				var resp string
+				require.NoError(t, c.Call(userCtx, "Replica.Get", "", &resp))
+				if first == "" {
+					first = resp
+				}
+				require.Equal(t, first, resp, "user %d", i)
+			}
+			replicas[first] = true
+		}
+		require.Greater(t, len(replicas), 1)
+	})
+}
+
+func TestConsistentHashRemap(t *testing.T) {
+	b := srpc.NewConsistentHashBalancer("key")
+	b.Update([]string{"a", "b", "c", "d", "e"})
+
+	pick := func(key string) string {
+		addr, done, err := b.Pick(t.Context(), srpc.Request{Metadata: srpc.Pairs("key", key)})
+		require.NoError(t, err)
+		done()
+		return addr
+	}
+
+	before := map[string]string{}
+	for i := range 1000 {
+		key := fmt.Sprint(i)
+		before[key] = pick(key)
+	}
+
+	// only keys of the removed address move
+	b.Update([]string{"a", "b", "c", "d"})
+	moved := 0
+	for key, addr := range before {
+		if addr != "e" {
+			require.Equal(t, addr, pick(key))
+			continue
+		}
+		require.NotEqual(t, "e", pick(key))
+		moved++
+	}
+	require.Greater(t, moved, 100)
+	require.Less(t, moved, 350)
+}
+
+func TestLeastOutstandingBalancer(t *testing.T) {
+	b := srpc.NewLeastOutstandingBalancer()
+
+	_, _, err := b.Pick(t.Context(), srpc.Request{})
+	require.ErrorIs(t, err, srpc.ErrNoAddrs)
+
+	b.Update([]string{"a", "b"})
+	first, done, err := b.Pick(t.Context(), srpc.Request{})
+	require.NoError(t, err)
+
+	// the busy address is avoided until its call is done
+	for range 3 {
+		addr, done2, err := b.Pick(t.Context(), srpc.Request{})
+		require.NoError(t, err)
+		require.NotEqual(t, first, addr)
+		done2()
+	}
+
+	done()
+	done() // done is idempotent
+	seen := map[string]bool{}
+	for range 2 {
+		addr, done, err := b.Pick(t.Context(), srpc.Request{})
+		require.NoError(t, err)
+		seen[addr] = true
+		done()
+	}
+	require.Len(t, seen, 2)
+}
@@ -0,0 +1,286 @@
+package srpc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by the calls, which are rejected by the open
+// circuit breaker without being sent.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of the circuit breaker.
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // calls are sent, failures are counted
+	BreakerOpen                         // calls fail fast with ErrCircuitOpen
+	BreakerHalfOpen                     // a few trial calls are sent to check the backend
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+
+	return ""
+}
+
+// BreakerKey identifies the circuit breaker. ServiceMethod is empty unless
+// [BreakerConfig.PerMethod] is set.
+type BreakerKey struct {
+	Addr          string
+	ServiceMethod ServiceMethod
+}
+
+// BreakerConfig configures the circuit breakers of the [Client]. Calls
+// failed with transport errors or [BreakerConfig.FailureStatuses] are
+// counted as failures, calls cancelled by the caller are not counted.
+//
+// Once the ratio of failed calls in the window reaches FailureRatio, the
+// breaker opens and the calls fail with [ErrCircuitOpen]. After OpenTimeout
+// the breaker lets HalfOpenCalls trial calls through: if all of them
+// succeed, the breaker closes, otherwise it opens again.
+type BreakerConfig struct {
+	// PerMethod keys the breakers by address and method, instead of
+	// address only.
+	PerMethod bool
+
+	// FailureRatio is the ratio of failed calls in the window, which trips
+	// the breaker. Zero means [DefaultBreakerFailureRatio].
+	FailureRatio float64
+
+	// MinCalls is the minimum number of calls in the window, before the
+	// breaker can trip. Zero means [DefaultBreakerMinCalls].
+	MinCalls int
+
+	// Window is the period, calls are counted over. Zero means
+	// [DefaultBreakerWindow].
+	Window time.Duration
+
+	// OpenTimeout is the time the breaker stays open. Zero means
+	// [DefaultBreakerOpenTimeout].
+	OpenTimeout time.Duration
+
+	// HalfOpenCalls is the number of trial calls in half-open state. Zero
+	// means 1.
+	HalfOpenCalls int
+
+	// FailureStatuses are the statuses of the response, which are counted
+	// as failures. Nil means [StatusInternalError] only.
+	FailureStatuses []StatusCode
+
+	// OnStateChange is called on every state change of the breaker, e.g. for
+	// alerting. It must not block.
+	OnStateChange func(key BreakerKey, from, to BreakerState)
+}
+
+const (
+	DefaultBreakerFailureRatio = 0.5
+	DefaultBreakerMinCalls     = 10
+	DefaultBreakerWindow       = 10 * time.Second
+	DefaultBreakerOpenTimeout  = 5 * time.Second
+)
+
+func (cfg BreakerConfig) withDefaults() BreakerConfig {
+	cfg.FailureRatio = tern(cfg.FailureRatio > 0, cfg.FailureRatio, DefaultBreakerFailureRatio)
+	cfg.MinCalls = tern(cfg.MinCalls > 0, cfg.MinCalls, DefaultBreakerMinCalls)
+	cfg.Window = tern(cfg.Window > 0, cfg.Window, DefaultBreakerWindow)
+	cfg.OpenTimeout = tern(cfg.OpenTimeout > 0, cfg.OpenTimeout, DefaultBreakerOpenTimeout)
+	cfg.HalfOpenCalls = tern(cfg.HalfOpenCalls > 0, cfg.HalfOpenCalls, 1)
+	if cfg.FailureStatuses == nil {
+		cfg.FailureStatuses = []StatusCode{StatusInternalError}
+	}
+
+	return cfg
+}
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored // e.g. the call is cancelled by the caller
+)
+
+// breakers keeps the circuit breakers of the client.
+type breakers struct {
+	cfg BreakerConfig
+
+	mu sync.Mutex
+	m  map[BreakerKey]*breaker
+}
+
+type breaker struct {
+	state BreakerState
+	gen   uint64 // incremented on every state change
+
+	windowStart     time.Time
+	calls, failures int
+
+	openedAt     time.Time
+	trials       int // trial calls in progress or finished in half-open state
+	trialsPassed int
+}
+
+func newBreakers(cfg BreakerConfig) *breakers {
+	return &breakers{
+		cfg: cfg.withDefaults(),
+		m:   make(map[BreakerKey]*breaker),
+	}
+}
+
+func (bs *breakers) key(addr string, serviceMethod ServiceMethod) BreakerKey {
+	if bs.cfg.PerMethod {
+		return BreakerKey{Addr: addr, ServiceMethod: serviceMethod}
+	}
+
+	return BreakerKey{Addr: addr}
+}
+
+// allow reports whether the call can be sent. If so, report must be called
+// with the outcome of the call.
+func (bs *breakers) allow(key BreakerKey) (report func(outcome), err error) {
+	bs.mu.Lock()
+
+	b, ok := bs.m[key]
+	if !ok {
+		b = &breaker{windowStart: time.Now()}
+		bs.m[key] = b
+	}
+
+	from := b.state
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= bs.cfg.OpenTimeout {
+		b.setState(BreakerHalfOpen)
+		b.trials, b.trialsPassed = 0, 0
+	}
+
+	switch b.state {
+	case BreakerOpen:
+		bs.mu.Unlock()
+		return nil, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.trials >= bs.cfg.HalfOpenCalls {
+			bs.mu.Unlock()
+			bs.notify(key, from, b.state)
+			return nil, ErrCircuitOpen
+		}
+		b.trials++
+	}
+	to, gen := b.state, b.gen
+	bs.mu.Unlock()
+
+	bs.notify(key, from, to)
+
+	var once sync.Once
+	return func(o outcome) {
+		once.Do(func() { bs.report(key, b, gen, o) })
+	}, nil
+}
+
+// report applies the outcome of the call admitted in the generation gen of
+// the state. Outcomes of the calls admitted before the state changed are
+// dropped, e.g. the late failure of the call sent before the breaker opened
+// is not taken for the failed trial.
+func (bs *breakers) report(key BreakerKey, b *breaker, gen uint64, o outcome) {
+	bs.mu.Lock()
+	if b.gen != gen {
+		bs.mu.Unlock()
+		return
+	}
+
+	from := b.state
+	switch b.state {
+	case BreakerClosed:
+		if time.Since(b.windowStart) >= bs.cfg.Window {
+			b.windowStart = time.Now()
+			b.calls, b.failures = 0, 0
+		}
+
+		if o == outcomeIgnored {
+			break
+		}
+
+		b.calls++
+		if o == outcomeFailure {
+			b.failures++
+		}
+
+		if b.calls >= bs.cfg.MinCalls && float64(b.failures)/float64(b.calls) >= bs.cfg.FailureRatio {
+			b.open()
+		}
+	case BreakerHalfOpen:
+		switch o {
+		case outcomeIgnored:
+			b.trials-- // let another trial through
+		case outcomeFailure:
+			b.open()
+		case outcomeSuccess:
+			b.trialsPassed++
+			if b.trialsPassed >= bs.cfg.HalfOpenCalls {
+				b.setState(BreakerClosed)
+				b.windowStart = time.Now()
+				b.calls, b.failures = 0, 0
+			}
+		}
+	}
+	to := b.state
+	bs.mu.Unlock()
+
+	bs.notify(key, from, to)
+}
+
+func (b *breaker) open() {
+	b.setState(BreakerOpen)
+	b.openedAt = time.Now()
+}
+
+func (b *breaker) setState(state BreakerState) {
+	b.state = state
+	b.gen++
+}
+
+func (bs *breakers) notify(key BreakerKey, from, to BreakerState) {
+	if from != to && bs.cfg.OnStateChange != nil {
+		bs.cfg.OnStateChange(key, from, to)
+	}
+}
+
+// callOutcome returns the outcome of the call, which returned resp or err.
+func (bs *breakers) callOutcome(ctx context.Context, resp Response, err error) outcome {
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return outcomeIgnored
+	case err != nil:
+		return outcomeFailure
+	case slices.Contains(bs.cfg.FailureStatuses, resp.StatusCode):
+		return outcomeFailure
+	}
+
+	return outcomeSuccess
+}
+
+// BreakerStates returns the states of the circuit breakers of the client.
+func (c *Client) BreakerStates() map[BreakerKey]BreakerState {
+	if c.breakers == nil {
+		return nil
+	}
+
+	c.breakers.mu.Lock()
+	defer c.breakers.mu.Unlock()
+
+	states := make(map[BreakerKey]BreakerState, len(c.breakers.m))
+	for key, b := range c.breakers.m {
+		states[key] = b.state
+	}
+
+	return states
+}
@@ -0,0 +1,173 @@
+package srpc_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/testdata"
+)
+
+// transitions records the state changes of the breakers.
+type transitions struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (tr *transitions) record(key srpc.BreakerKey, from, to srpc.BreakerState) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.log = append(tr.log, fmt.Sprintf("%s->%s", from, to))
+}
+
+func (tr *transitions) get() []string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	return append([]string(nil), tr.log...)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := t.Context()
+
+	newClient := func(t *testing.T, failures int64, perMethod bool) (*srpc.Client, string, *transitions, func() int64) {
+		flaky, calls := failFirstCalls(failures)
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(flaky))
+
+		tr := &transitions{}
+		c := server.Client(t, srpc.WithCircuitBreaker(srpc.BreakerConfig{
+			PerMethod:     perMethod,
+			FailureRatio:  0.5,
+			MinCalls:      4,
+			OpenTimeout:   50 * time.Millisecond,
+			OnStateChange: tr.record,
+		}))
+
+		return c, server.Addr, tr, calls.Load
+	}
+
+	add := func(c *srpc.Client) error {
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		return err
+	}
+
+	t.Run("trips and recovers", func(t *testing.T) {
+		c, addr, tr, calls := newClient(t, 4, false)
+
+		for range 4 {
+			require.Equal(t, srpc.CodeUnavailable, srpc.CodeOf(add(c)))
+		}
+
+		// fails fast without calling the server
+		require.ErrorIs(t, add(c), srpc.ErrCircuitOpen)
+		require.EqualValues(t, 4, calls())
+		require.Equal(t, srpc.BreakerOpen, c.BreakerStates()[srpc.BreakerKey{Addr: addr}])
+
+		// trial call closes the breaker
+		time.Sleep(60 * time.Millisecond)
+		require.NoError(t, add(c))
+		require.NoError(t, add(c))
+		require.Equal(t, []string{"closed->open", "open->half-open", "half-open->closed"}, tr.get())
+	})
+
+	t.Run("failed trial opens again", func(t *testing.T) {
+		c, _, tr, calls := newClient(t, 5, false)
+
+		for range 4 {
+			require.Error(t, add(c))
+		}
+		require.ErrorIs(t, add(c), srpc.ErrCircuitOpen)
+
+		time.Sleep(60 * time.Millisecond)
+		require.Equal(t, srpc.CodeUnavailable, srpc.CodeOf(add(c)))
+		require.ErrorIs(t, add(c), srpc.ErrCircuitOpen)
+		require.EqualValues(t, 5, calls())
+		require.Equal(t, []string{"closed->open", "open->half-open", "half-open->open"}, tr.get())
+	})
+
+	t.Run("successes keep it closed", func(t *testing.T) {
+		c, _, tr, _ := newClient(t, 1, false)
+
+		for range 10 {
+			add(c)
+		}
+		require.NoError(t, add(c))
+		require.Empty(t, tr.get())
+	})
+
+	t.Run("per method", func(t *testing.T) {
+		c, addr, _, _ := newClient(t, 100, true)
+
+		for range 4 {
+			require.Error(t, add(c))
+		}
+		require.ErrorIs(t, add(c), srpc.ErrCircuitOpen)
+
+		_, err := testdata.NewTestServiceClient(c).Divide(ctx, testdata.DivideReq{A: 4, B: 2})
+		require.NotErrorIs(t, err, srpc.ErrCircuitOpen)
+
+		require.Equal(t, map[srpc.BreakerKey]srpc.BreakerState{
+			{Addr: addr, ServiceMethod: "TestService.Add"}:    srpc.BreakerOpen,
+			{Addr: addr, ServiceMethod: "TestService.Divide"}: srpc.BreakerClosed,
+		}, c.BreakerStates())
+	})
+
+	t.Run("call admitted before open", func(t *testing.T) {
+		// Divide calls wait for the release, so they are in flight while
+		// the breaker changes the state
+		started, release := make(chan struct{}), make(chan struct{})
+		block := func(ctx context.Context, req srpc.Request, next srpc.UnaryHandler) srpc.Response {
+			if req.ServiceMethod == "TestService.Divide" {
+				started <- struct{}{}
+				<-release
+			}
+			return next(ctx, req)
+		}
+		flaky, _ := failFirstCalls(4)
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(block, flaky))
+
+		tr := &transitions{}
+		c := server.Client(t, srpc.WithCircuitBreaker(srpc.BreakerConfig{
+			FailureRatio:  0.5,
+			MinCalls:      4,
+			OpenTimeout:   50 * time.Millisecond,
+			OnStateChange: tr.record,
+		}))
+
+		divide := func(errs chan<- error) {
+			_, err := testdata.NewTestServiceClient(c).Divide(ctx, testdata.DivideReq{A: 4, B: 2})
+			errs <- err
+		}
+
+		staleErr := make(chan error, 1)
+		go divide(staleErr)
+		<-started
+
+		for range 4 {
+			require.Error(t, add(c))
+		}
+		require.ErrorIs(t, add(c), srpc.ErrCircuitOpen)
+
+		time.Sleep(60 * time.Millisecond)
+		trialErr := make(chan error, 1)
+		go divide(trialErr)
+		<-started
+
+		// success of the call sent before the breaker opened is not the
+		// passed trial
+		release <- struct{}{}
+		require.NoError(t, <-staleErr)
+		require.Equal(t, srpc.BreakerHalfOpen, c.BreakerStates()[srpc.BreakerKey{Addr: server.Addr}])
+		require.ErrorIs(t, add(c), srpc.ErrCircuitOpen)
+
+		release <- struct{}{}
+		require.NoError(t, <-trialErr)
+		require.Equal(t, []string{"closed->open", "open->half-open", "half-open->closed"}, tr.get())
+	})
+}
@@ -0,0 +1,113 @@
+package srpc
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// _maxPooledBufferSize is the capacity of the buffer, above which it is
+// dropped instead of being returned to the pool, so a few large messages
+// don't pin the memory.
+const _maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > _maxPooledBufferSize {
+		return
+	}
+
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// encodeBuffered encodes src into the pooled buffer at once, so the complete
+// body is sent without the pipe and the goroutine encoding into it. Encoders
+// with [Marshaler] append to the buffer directly.
+func encodeBuffered(enc Encoder, src any) (io.ReadCloser, error) {
+	buf := getBuffer()
+
+	var err error
+	if m, ok := enc.(Marshaler); ok {
+		var b []byte
+		b, err = m.AppendMarshal(buf.AvailableBuffer(), src)
+		buf.Write(b)
+	} else {
+		err = enc.Encode(buf, src)
+	}
+	if err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+
+	return &bufferBody{buf: buf}, nil
+}
+
+// decodeBuffered decodes the complete body into dst. Decoders with
+// [Unmarshaler] decode the body read into the pooled buffer, others decode
+// the body as is.
+func decodeBuffered(dec Decoder, body io.Reader, dst any) error {
+	u, ok := dec.(Unmarshaler)
+	if !ok {
+		return dec.Decode(body, dst)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if _, err := buf.ReadFrom(body); err != nil {
+		return err
+	}
+
+	return u.Unmarshal(buf.Bytes(), dst)
+}
+
+// bufferBody is the body encoded into the pooled buffer, which is returned to
+// the pool on Close. Reads after Close fail with [io.ErrClosedPipe], as they
+// did with the pipe, so the late read of the transport (e.g. http.Transport
+// closes the request body asynchronously) never sees the buffer of another
+// call.
+type bufferBody struct {
+	mu  sync.Mutex
+	buf *bytes.Buffer
+}
+
+func (b *bufferBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.buf == nil {
+		return 0, io.ErrClosedPipe
+	}
+
+	return b.buf.Read(p)
+}
+
+// Len returns the number of the unread bytes of the body.
+func (b *bufferBody) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.buf == nil {
+		return 0
+	}
+
+	return b.buf.Len()
+}
+
+func (b *bufferBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.buf != nil {
+		putBuffer(b.buf)
+		b.buf = nil
+	}
+
+	return nil
+}
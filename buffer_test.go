@@ -0,0 +1,102 @@
+package srpc_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/transport/inmem"
+	"github.com/tymbaca/srpc/transport/testdata"
+)
+
+// marshalCodec is JSON, which counts the encodings and decodings of the
+// complete bodies.
+type marshalCodec struct {
+	srpc.Codec
+	marshals, unmarshals atomic.Int32
+	fail                 bool
+}
+
+func (c *marshalCodec) AppendMarshal(b []byte, src any) ([]byte, error) {
+	c.marshals.Add(1)
+	if c.fail {
+		return b, errors.New("can't marshal")
+	}
+
+	data, err := json.Marshal(src)
+	return append(b, data...), err
+}
+
+func (c *marshalCodec) Unmarshal(data []byte, dst any) error {
+	c.unmarshals.Add(1)
+	return json.Unmarshal(data, dst)
+}
+
+func TestBufferedCodec(t *testing.T) {
+	ctx := t.Context()
+	cluster := inmem.New()
+	serverPeer := cluster.NewPeer()
+
+	serverCodec := &marshalCodec{Codec: codec.JSON}
+	server := testdata.NewTestServiceServer(srpc.NewServer(serverCodec))
+	t.Cleanup(func() { server.Close() })
+	go server.Start(ctx, serverPeer.Listen())
+
+	t.Run("unary", func(t *testing.T) {
+		clientCodec := &marshalCodec{Codec: codec.JSON}
+		c := srpc.NewClient(serverPeer.Addr(), clientCodec, cluster.NewPeer())
+		defer c.Close()
+
+		resp, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.NoError(t, err)
+		require.Equal(t, 3, resp.Result)
+		require.EqualValues(t, 1, clientCodec.marshals.Load())
+		require.EqualValues(t, 1, clientCodec.unmarshals.Load())
+	})
+
+	t.Run("server stream", func(t *testing.T) {
+		clientCodec := &marshalCodec{Codec: codec.JSON}
+		c := srpc.NewClient(serverPeer.Addr(), clientCodec, cluster.NewPeer())
+		defer c.Close()
+
+		// the request is complete, the streamed messages are decoded as is
+		var got []int
+		for resp, err := range testdata.NewTestServiceClient(c).Count(ctx, testdata.CountReq{N: 3}) {
+			require.NoError(t, err)
+			got = append(got, resp.I)
+		}
+		require.Equal(t, []int{0, 1, 2}, got)
+		require.EqualValues(t, 1, clientCodec.marshals.Load())
+		require.Zero(t, clientCodec.unmarshals.Load())
+	})
+
+	t.Run("request encode error", func(t *testing.T) {
+		c := srpc.NewClient(serverPeer.Addr(), &marshalCodec{Codec: codec.JSON, fail: true}, cluster.NewPeer())
+		defer c.Close()
+
+		before := serverCodec.unmarshals.Load()
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.ErrorContains(t, err, "encode request: can't marshal")
+		require.Equal(t, before, serverCodec.unmarshals.Load(), "request is not sent")
+	})
+
+	t.Run("response encode error", func(t *testing.T) {
+		cluster := inmem.New()
+		serverPeer := cluster.NewPeer()
+		server := testdata.NewTestServiceServer(srpc.NewServer(&marshalCodec{Codec: codec.JSON, fail: true}))
+		t.Cleanup(func() { server.Close() })
+		go server.Start(ctx, serverPeer.Listen())
+
+		// unregistered codec, so the server uses its own
+		c := srpc.NewClient(serverPeer.Addr(), codec.ToCodec(json.NewEncoder, json.NewDecoder), cluster.NewPeer())
+		defer c.Close()
+
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.Equal(t, srpc.CodeInternal, srpc.CodeOf(err))
+		require.ErrorContains(t, err, "encode response: can't marshal")
+	})
+}
@@ -5,8 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-
-	"github.com/tymbaca/srpc/pkg/pipe"
 )
 
 var (
@@ -14,54 +12,371 @@ var (
 	ErrTransportError = errors.New("transport error")
 )
 
-func NewClient(addr string, codec Codec, connector Connector) *Client {
-	return &Client{
+// NewClient returns the client of the server at addr. If the client is
+// created [WithResolver], addr is ignored and the calls are sent to the
+// resolved addresses.
+func NewClient(addr string, codec Codec, connector Connector, opts ...ClientOption) *Client {
+	c := &Client{
 		addr:      addr,
 		codec:     codec,
 		connector: connector,
 	}
+	c.contentType, _ = codecName(codec)
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	c.pool = newPool(connector, c.poolConfig)
+	c.invoker = chainClientInterceptors(c.interceptors, c.invoke)
+
+	if c.resolver != nil {
+		var ctx context.Context
+		ctx, c.stopResolver = context.WithCancel(context.Background())
+		c.resolved = newResolvedAddrs()
+		if c.balancer == nil {
+			c.balancer = NewRoundRobinBalancer()
+		}
+		go c.resolved.watch(ctx, c.resolver, func(addrs []string) {
+			c.balancer.Update(addrs)
+			c.pool.update(addrs)
+		})
+	}
+
+	return c
 }
 
 type Client struct {
-	addr      string
-	codec     Codec
-	connector Connector
+	addr        string
+	codec       Codec
+	contentType string // name of the registered codec, see [RegisterCodec]
+	connector   Connector
+
+	poolConfig PoolConfig
+	pool       *pool
+
+	interceptors []UnaryClientInterceptor
+	invoker      UnaryInvoker
+
+	retry               RetryPolicy
+	methodRetryPolicies map[ServiceMethod]RetryPolicy
+
+	hedgingPolicies map[ServiceMethod]HedgingPolicy
+	hedgeMetrics    hedgeMetrics
+
+	breakers *breakers
+
+	compression *CompressionConfig
+
+	resolver     Resolver
+	balancer     Balancer
+	resolved     *resolvedAddrs
+	stopResolver context.CancelFunc
 }
 
-// TODO: check metadata in context
-// TODO: timeouts? > but we support context
+// Close closes idle connections. Connections of the calls in progress are
+// closed once the calls finish. Subsequent calls fail with [ErrClientClosed].
+func (c *Client) Close() error {
+	if c.stopResolver != nil {
+		c.stopResolver()
+	}
+
+	return c.pool.close()
+}
+
+// PoolStats returns the statistics of the connection pool per address.
+func (c *Client) PoolStats() map[string]PoolStats {
+	return c.pool.stats()
+}
+
+// Call calls the serviceMethod on the remote server. Metadata attached to ctx
+// with [NewOutgoingContext] or [AppendToOutgoingContext] is sent along with
+// the request. The deadline of ctx is sent as well, see [TimeoutKey], and
+// the name of the codec, if it is registered, see [RegisterCodec].
+//
+// Failed calls are retried according to the [RetryPolicy] of the method or
+// hedged according to its [HedgingPolicy].
+func (c *Client) Call(ctx context.Context, serviceMethod ServiceMethod, req any, resp any, opts ...CallOption) error {
+	reqBody, err := c.replayableBody(req)
+	if err != nil {
+		return err
+	}
 
-func (c *Client) Call(ctx context.Context, serviceMethod ServiceMethod, req any, resp any) error {
-	conn, err := c.connector.Connect(ctx, c.addr)
+	body, finish, err := c.start(ctx, serviceMethod, reqBody, opts)
 	if err != nil {
-		return fmt.Errorf("connect %s: %w", c.addr, err)
+		return err
 	}
 
-	connResp, err := conn.Do(ctx, Request{
+	reuse := false
+	defer func() { finish(reuse) }()
+
+	err = decodeBuffered(c.codec, body, resp)
+	if err != nil {
+		return fmt.Errorf("decode response body: %w", err)
+	}
+
+	reuse = drain(body)
+
+	return nil
+}
+
+// encodeBody encodes the complete body of req, see [Marshaler].
+func (c *Client) encodeBody(req any) (io.ReadCloser, error) {
+	body, err := encodeBuffered(c.codec, req)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+
+	return body, nil
+}
+
+// replayableBody returns the body of req, which can be encoded again for
+// retries.
+func (c *Client) replayableBody(req any) (requestBody, error) {
+	body, err := c.encodeBody(req)
+	if err != nil {
+		return requestBody{}, err
+	}
+
+	return requestBody{
+		body:    body,
+		getBody: func() (io.ReadCloser, error) { return c.encodeBody(req) },
+	}, nil
+}
+
+// requestBody is the body of the request with the optional way to replay
+// it, see [Request.GetBody].
+type requestBody struct {
+	body    io.ReadCloser
+	getBody func() (io.ReadCloser, error)
+}
+
+// start sends the request with reqBody and checks the status of the
+// response, retrying or hedging the attempts if possible. It returns the body of
+// successful response, finish must be called once the body is handled,
+// reporting whether the connection can be reused.
+func (c *Client) start(ctx context.Context, serviceMethod ServiceMethod, reqBody requestBody, opts []CallOption) (body io.Reader, finish func(reuse bool), err error) {
+	callOpts := newCallOptions(opts)
+
+	md, _ := FromOutgoingContext(ctx)
+	if md == nil {
+		md = Metadata{}
+	}
+	if err := checkReservedKeys(md); err != nil {
+		reqBody.body.Close()
+		return nil, nil, fmt.Errorf("outgoing metadata: %w", err)
+	}
+	if c.contentType != "" {
+		md.Set(ContentTypeKey, c.contentType)
+	}
+	if c.compression != nil && !c.compression.disabled(serviceMethod) {
+		md.Set(AcceptEncodingKey, compressorNames()...)
+	}
+
+	if policy, ok := c.hedgingPolicies[serviceMethod]; ok && policy.MaxAttempts > 1 && reqBody.getBody != nil {
+		return c.hedge(ctx, serviceMethod, md, reqBody, policy, callOpts)
+	}
+
+	policy := c.retryPolicy(serviceMethod)
+	for attempt := 1; ; attempt++ {
+		var retryable bool
+		body, finish, retryable, err = c.attempt(ctx, serviceMethod, md.Copy(), reqBody, policy, callOpts)
+		if err == nil || !retryable || attempt >= policy.MaxAttempts || reqBody.getBody == nil {
+			return body, finish, err
+		}
+
+		if !waitBackoff(ctx, policy.backoff(attempt)) {
+			return nil, nil, err
+		}
+
+		next, bodyErr := reqBody.getBody()
+		if bodyErr != nil {
+			return nil, nil, err
+		}
+		reqBody.body = next
+	}
+}
+
+// attempt sends the request once. It reports whether the failed attempt can
+// be retried according to policy.
+func (c *Client) attempt(ctx context.Context, serviceMethod ServiceMethod, md Metadata, reqBody requestBody, policy RetryPolicy, callOpts callOptions) (body io.Reader, finish func(reuse bool), retryable bool, err error) {
+	reqBody, err = c.compressRequest(serviceMethod, md, reqBody)
+	if err != nil {
+		reqBody.body.Close()
+		return nil, nil, false, err
+	}
+
+	ctx, conns := withCallConns(ctx)
+	finish = func(reuse bool) {
+		reqBody.body.Close() // in case request was never sent
+		conns.release(c.pool, reuse)
+	}
+
+	connResp, err := c.invoker(ctx, Request{
 		ServiceMethod: serviceMethod,
-		Metadata:      Metadata{}, // TODO:
-		Body:          pipe.ToReader(func(w io.Writer) error { return c.codec.Encode(w, req) }),
+		Metadata:      md,
+		Body:          reqBody.body,
+		GetBody:       reqBody.getBody,
 	})
 	if err != nil {
-		return fmt.Errorf("send request: %w", err)
+		finish(false)
+		return nil, nil, policy.retryable(ctx, connResp, err), err
+	}
+
+	header, trailer := splitTrailer(connResp.Metadata)
+	if callOpts.header != nil {
+		*callOpts.header = header
+	}
+	if callOpts.trailer != nil {
+		*callOpts.trailer = trailer
 	}
 
 	if connResp.StatusCode != StatusOK {
-		coreErr := ErrTransportError
-		if connResp.StatusCode == StatusErrorFromService {
-			coreErr = ErrServiceError
+		finish(true)
+		return nil, nil, policy.retryable(ctx, connResp, nil), statusError(connResp)
+	}
+
+	body, err = decompressBody(connResp.Body, connResp.Metadata)
+	if err != nil {
+		finish(false)
+		return nil, nil, false, err
+	}
+
+	return body, finish, false, nil
+}
+
+// compressRequest returns the body of the request compressed according to
+// the compression config of the client, setting [EncodingKey] of md if it is
+// compressed. Replayable bodies are complete, others are streamed. The
+// replayed bodies of the compressed request are compressed the same way.
+func (c *Client) compressRequest(serviceMethod ServiceMethod, md Metadata, reqBody requestBody) (requestBody, error) {
+	cfg := c.compression
+	if cfg == nil || cfg.Compressor == "" || cfg.disabled(serviceMethod) {
+		return reqBody, nil
+	}
+
+	comp, ok := CompressorByName(cfg.Compressor)
+	if !ok {
+		return reqBody, fmt.Errorf("compressor %q is not registered", cfg.Compressor)
+	}
+
+	body, compressed, err := compressBody(reqBody.body, comp, cfg.minSize(), reqBody.getBody != nil)
+	reqBody.body = body
+	if err != nil {
+		return reqBody, fmt.Errorf("encode request: %w", err)
+	}
+	if !compressed {
+		return reqBody, nil
+	}
+
+	md.Set(EncodingKey, cfg.Compressor)
+	if getBody := reqBody.getBody; getBody != nil {
+		reqBody.getBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+
+			// the replayed body must match the encoding, whatever its size
+			body, _, err = compressBody(body, comp, 0, true)
+			return body, err
 		}
-		if connResp.Error != nil {
-			return fmt.Errorf("%w: %s", coreErr, connResp.Error)
-		} else {
-			return fmt.Errorf("%w: (no error message)", coreErr)
+	}
+
+	return reqBody, nil
+}
+
+// statusError returns the error for the response with non-OK status.
+func statusError(resp Response) error {
+	coreErr := ErrTransportError
+	if resp.StatusCode == StatusErrorFromService {
+		coreErr = ErrServiceError
+	}
+
+	if resp.Error != nil {
+		return fmt.Errorf("%w: %w", coreErr, toError(resp.Error, resp.StatusCode.errorCode()))
+	} else {
+		return fmt.Errorf("%w: %w", coreErr, &Error{Code: resp.StatusCode.errorCode(), Message: "(no error message)"})
+	}
+}
+
+// _maxDrain is the maximum amount of unread response body, that is discarded
+// to reuse the connection.
+const _maxDrain = 4 << 10
+
+// drain reads the rest of the body, reporting whether it is read completely.
+func drain(body io.Reader) bool {
+	if body == nil {
+		return true
+	}
+
+	n, err := io.CopyN(io.Discard, body, _maxDrain+1)
+	return errors.Is(err, io.EOF) && n <= _maxDrain
+}
+
+// invoke is the last step of interceptor chain, it sends the request to
+// the remote server.
+func (c *Client) invoke(ctx context.Context, req Request) (Response, error) {
+	addr, done, err := c.pickAddr(ctx, req)
+	if err != nil {
+		return Response{}, fmt.Errorf("pick address: %w", err)
+	}
+
+	report := func(resp Response, err error) {}
+	if c.breakers != nil {
+		key := c.breakers.key(addr, req.ServiceMethod)
+		allowed, err := c.breakers.allow(key)
+		if err != nil {
+			done()
+			return Response{}, fmt.Errorf("%s: %w", addr, err)
 		}
+		report = func(resp Response, err error) { allowed(c.breakers.callOutcome(ctx, resp, err)) }
 	}
 
-	err = c.codec.Decode(connResp.Body, resp)
+	conn, err := c.pool.get(ctx, addr)
 	if err != nil {
-		return fmt.Errorf("decode response body: %w", err)
+		report(Response{}, err)
+		done()
+		return Response{}, fmt.Errorf("connect %s: %w", addr, err)
 	}
 
-	return nil
+	// pool may take a while, so the timeout is set right before sending
+	req.Metadata = setTimeout(ctx, req.Metadata)
+
+	resp, err := conn.Do(ctx, req)
+	report(resp, err)
+	if err != nil {
+		c.pool.put(addr, conn, false)
+		done()
+		return Response{}, fmt.Errorf("send request: %w", err)
+	}
+
+	// the response body is still to be read, conn is released by the caller
+	conns, ok := ctx.Value(callConnsKey{}).(*callConns)
+	if !ok {
+		c.pool.put(addr, conn, false)
+		done()
+		return Response{}, fmt.Errorf("send request: invoker is called outside of Client.Call")
+	}
+	conns.add(addr, conn, done)
+
+	return resp, nil
+}
+
+// pickAddr returns the address to send the request to. done must be called
+// once the call is finished.
+func (c *Client) pickAddr(ctx context.Context, req Request) (addr string, done func(), err error) {
+	if addr, ok := addrFromContext(ctx); ok {
+		return addr, noop, nil
+	}
+
+	if c.resolved == nil {
+		return c.addr, noop, nil
+	}
+
+	if err := c.resolved.wait(ctx); err != nil {
+		return "", nil, err
+	}
+
+	return c.balancer.Pick(ctx, req)
 }
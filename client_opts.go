@@ -0,0 +1,118 @@
+package srpc
+
+type ClientOption func(c *Client)
+
+// WithUnaryClientInterceptors appends interceptors to the client's chain.
+// Interceptors are called in the provided order, the first one being the
+// outermost.
+func WithUnaryClientInterceptors(interceptors ...UnaryClientInterceptor) ClientOption {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// WithPool configures the pool of connections. By default at most
+// [DefaultMaxIdle] idle connections are kept per address.
+func WithPool(cfg PoolConfig) ClientOption {
+	return func(c *Client) {
+		c.poolConfig = cfg
+	}
+}
+
+// WithRetryPolicy sets the retry policy of all methods, which have no own
+// policy set with [WithMethodRetryPolicy]. By default calls are not retried.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithMethodRetryPolicy sets the retry policy of serviceMethod, overriding
+// the one set with [WithRetryPolicy].
+func WithMethodRetryPolicy(serviceMethod ServiceMethod, policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		if c.methodRetryPolicies == nil {
+			c.methodRetryPolicies = make(map[ServiceMethod]RetryPolicy)
+		}
+		c.methodRetryPolicies[serviceMethod] = policy
+	}
+}
+
+// WithHedgingPolicy sets the hedging policy of serviceMethod.
+func WithHedgingPolicy(serviceMethod ServiceMethod, policy HedgingPolicy) ClientOption {
+	return func(c *Client) {
+		if c.hedgingPolicies == nil {
+			c.hedgingPolicies = make(map[ServiceMethod]HedgingPolicy)
+		}
+		c.hedgingPolicies[serviceMethod] = policy
+	}
+}
+
+// WithResolver makes the client send the calls to the addresses produced by
+// r, instead of the one passed to [NewClient]. The address of every call is
+// picked by the [Balancer]. Calls wait for the first set of addresses.
+func WithResolver(r Resolver) ClientOption {
+	return func(c *Client) {
+		c.resolver = r
+	}
+}
+
+// WithBalancer sets the balancer, which picks the address for every call
+// among the addresses of the [Resolver]. It is [NewRoundRobinBalancer] by
+// default. The balancer is not used without the resolver.
+func WithBalancer(b Balancer) ClientOption {
+	return func(c *Client) {
+		c.balancer = b
+	}
+}
+
+// WithCircuitBreaker enables the circuit breakers per address (and
+// optionally per method), see [BreakerConfig].
+func WithCircuitBreaker(cfg BreakerConfig) ClientOption {
+	return func(c *Client) {
+		c.breakers = newBreakers(cfg)
+	}
+}
+
+// WithClientCompression makes the client compress the requests and accept
+// the compressed responses, see [CompressionConfig].
+func WithClientCompression(cfg CompressionConfig) ClientOption {
+	return func(c *Client) {
+		c.compression = &cfg
+	}
+}
+
+// CallOption configures a single [Client.Call].
+type CallOption func(o *callOptions)
+
+type callOptions struct {
+	header  *Metadata
+	trailer *Metadata
+}
+
+func newCallOptions(opts []CallOption) callOptions {
+	var callOpts callOptions
+	for _, o := range opts {
+		o(&callOpts)
+	}
+
+	return callOpts
+}
+
+// Header stores the metadata of the response into md. It is set even if the
+// call fails, as long as the response was received.
+func Header(md *Metadata) CallOption {
+	return func(o *callOptions) {
+		o.header = md
+	}
+}
+
+// Trailer stores the trailer of the response into md, see [SetTrailer]. It
+// is set even if the call fails, as long as the response was received. The
+// trailer of the streamed response is set once the stream is over, i.e. its
+// iteration is finished or Recv returns an error.
+func Trailer(md *Metadata) CallOption {
+	return func(o *callOptions) {
+		o.trailer = md
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/tymbaca/srpc"
+)
+
+type statusKey struct{}
+
+// callStatus is the status of the response, if it is received.
+type callStatus struct {
+	code     srpc.StatusCode
+	received bool
+}
+
+// withStatus returns a copy of ctx, which makes [captureStatus] store the
+// status of the response to the returned callStatus.
+func withStatus(ctx context.Context) (context.Context, *callStatus) {
+	status := &callStatus{}
+	return context.WithValue(ctx, statusKey{}, status), status
+}
+
+func captureStatus(ctx context.Context, req srpc.Request, invoke srpc.UnaryInvoker) (srpc.Response, error) {
+	resp, err := invoke(ctx, req)
+	if status, ok := ctx.Value(statusKey{}).(*callStatus); ok && err == nil {
+		*status = callStatus{code: resp.StatusCode, received: true}
+	}
+
+	return resp, err
+}
+
+// call calls the method with the JSON body from args and prints the status,
+// metadata and body of the response.
+func call(ctx context.Context, client *srpc.Client, serviceMethod string, args []string) error {
+	if _, _, ok := srpc.ServiceMethod(serviceMethod).Split(); !ok {
+		return fmt.Errorf("invalid method %q, expected Service.Method", serviceMethod)
+	}
+
+	body, err := readBody(args)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	if !json.Valid(body) {
+		return fmt.Errorf("body is not valid JSON")
+	}
+
+	ctx, status := withStatus(ctx)
+	var (
+		header, trailer srpc.Metadata
+		resp            json.RawMessage
+	)
+	callErr := client.Call(ctx, srpc.ServiceMethod(serviceMethod), json.RawMessage(body), &resp, srpc.Header(&header), srpc.Trailer(&trailer))
+
+	if status.received {
+		fmt.Printf("status: %s\n", status.code)
+	}
+	if len(header) > 0 {
+		fmt.Println("metadata:")
+		printMetadata(header)
+	}
+	if len(trailer) > 0 {
+		fmt.Println("trailer:")
+		printMetadata(trailer)
+	}
+	if callErr != nil {
+		return callErr
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, resp, "", "  "); err != nil {
+		out.Reset()
+		out.Write(resp)
+	}
+	out.WriteByte('\n')
+	_, err = out.WriteTo(os.Stdout)
+
+	return err
+}
+
+func printMetadata(md srpc.Metadata) {
+	for _, key := range slices.Sorted(maps.Keys(md)) {
+		fmt.Printf("  %s: %s\n", key, strings.Join(md[key], ", "))
+	}
+}
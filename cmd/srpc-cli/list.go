@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/reflection"
+)
+
+var errNoReflection = errors.New("server does not support reflection (Reflection service is not registered)")
+
+// list prints the services of the server, or the methods of the service
+// from args, using the reflection service.
+func list(ctx context.Context, client *srpc.Client, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("unexpected arguments after service: %v", args[1:])
+	}
+
+	ctx, status := withStatus(ctx)
+	refl := reflection.NewReflectionClient(client)
+
+	if len(args) == 0 {
+		resp, err := refl.ListServices(ctx, reflection.ListServicesReq{})
+		if err != nil {
+			return reflectionError(status, err)
+		}
+
+		for _, name := range resp.Services {
+			fmt.Println(name)
+		}
+		return nil
+	}
+
+	resp, err := refl.DescribeService(ctx, reflection.DescribeServiceReq{Service: args[0]})
+	if err != nil {
+		return reflectionError(status, err)
+	}
+
+	fmt.Println(resp.Service.Name)
+	for _, m := range resp.Service.Methods {
+		fmt.Printf("  %s (%s)\n", m.Name, m.Kind)
+		fmt.Printf("    request:  %s\n", messageString(m.Request))
+		fmt.Printf("    response: %s\n", messageString(m.Response))
+	}
+
+	return nil
+}
+
+func reflectionError(status *callStatus, err error) error {
+	if status.received && status.code == srpc.StatusServiceNotFound {
+		return errNoReflection
+	}
+
+	return err
+}
+
+// messageString returns the type of the message with its fields, e.g.
+// "pkg.AddReq {A int; B int}".
+func messageString(t srpc.TypeDesc) string {
+	if len(t.Fields) == 0 {
+		return typeString(t)
+	}
+
+	fields := make([]string, 0, len(t.Fields))
+	for _, f := range t.Fields {
+		field := f.Name + " " + typeString(f.Type)
+		if f.Tag != "" {
+			field += " `" + f.Tag + "`"
+		}
+		fields = append(fields, field)
+	}
+
+	return fmt.Sprintf("%s {%s}", typeString(t), strings.Join(fields, "; "))
+}
+
+// typeString returns the Go-like name of the type.
+func typeString(t srpc.TypeDesc) string {
+	switch {
+	case t.Name != "":
+		return t.Name
+	case t.Kind == "ptr" && t.Elem != nil:
+		return "*" + typeString(*t.Elem)
+	case t.Kind == "slice" && t.Elem != nil:
+		return "[]" + typeString(*t.Elem)
+	case t.Kind == "map" && t.Key != nil && t.Elem != nil:
+		return fmt.Sprintf("map[%s]%s", typeString(*t.Key), typeString(*t.Elem))
+	}
+
+	return t.Kind
+}
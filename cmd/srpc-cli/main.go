@@ -0,0 +1,122 @@
+// Command srpc-cli calls the methods of srpc servers from the command line,
+// so they can be debugged without writing Go code:
+//
+//	srpc-cli -addr localhost:8080 -H tenant=acme TestService.Add '{"A": 1, "B": 2}'
+//	srpc-cli -addr localhost:8080 list
+//	srpc-cli -addr localhost:8080 list TestService
+//
+// Requests and responses are JSON, so the server must use [codec.JSON].
+// Only unary methods can be called. The list subcommand requires the server
+// to register the [reflection.Reflection] service.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+)
+
+const version = "v0.0.1"
+
+func main() {
+	for _, t := range transports {
+		if t.flags != nil {
+			t.flags(flag.CommandLine)
+		}
+	}
+
+	transportName := flag.String("transport", "http", "transport to connect with: "+transportNames())
+	addr := flag.String("addr", "", "address of the server (required)")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout of the call, 0 means no timeout")
+	md := metadataFlag{}
+	flag.Var(md, "H", "metadata of the call as key=value, can be repeated")
+	flag.Usage = usage
+	flag.Parse()
+
+	t, ok := transports[*transportName]
+	if !ok {
+		fatalf("unknown transport %q, available: %s", *transportName, transportNames())
+	}
+	if *addr == "" {
+		fatalf("-addr is required")
+	}
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	ctx = srpc.NewOutgoingContext(ctx, srpc.Metadata(md))
+
+	connector, connAddr := t.connect(*addr)
+	client := srpc.NewClient(connAddr, codec.JSON, connector, srpc.WithUnaryClientInterceptors(captureStatus))
+	defer client.Close()
+
+	var err error
+	switch args := flag.Args(); args[0] {
+	case "list":
+		err = list(ctx, client, args[1:])
+	default:
+		err = call(ctx, client, args[0], args[1:])
+	}
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "srpc-cli %s\n\n", version)
+	fmt.Fprintf(out, "Usage:\n")
+	fmt.Fprintf(out, "  srpc-cli [flags] Service.Method [body]  call the method, body is JSON or - to read it from stdin\n")
+	fmt.Fprintf(out, "  srpc-cli [flags] list [Service]         list the services or the methods of the service\n\n")
+	fmt.Fprintf(out, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+// metadataFlag collects key=value pairs into the metadata.
+type metadataFlag srpc.Metadata
+
+func (f metadataFlag) String() string {
+	return ""
+}
+
+func (f metadataFlag) Set(s string) error {
+	key, val, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("metadata must be key=value, got %q", s)
+	}
+
+	srpc.Metadata(f).Append(key, val)
+	return nil
+}
+
+func readBody(args []string) ([]byte, error) {
+	switch {
+	case len(args) == 0:
+		return []byte("{}"), nil
+	case len(args) > 1:
+		return nil, fmt.Errorf("unexpected arguments after body: %v", args[1:])
+	case args[0] == "-":
+		return io.ReadAll(os.Stdin)
+	}
+
+	return []byte(args[0]), nil
+}
+
+func fatalf(formatStr string, args ...any) {
+	fmt.Fprintf(os.Stderr, "srpc-cli: "+formatStr+"\n", args...)
+	os.Exit(1)
+}
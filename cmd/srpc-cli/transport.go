@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/tymbaca/srpc"
+	httptransport "github.com/tymbaca/srpc/transport/http"
+	tcptransport "github.com/tymbaca/srpc/transport/tcp"
+)
+
+// transport plugs the network transport into the cli.
+type transport struct {
+	// flags registers the flags of the transport (optional).
+	flags func(fs *flag.FlagSet)
+
+	// connect returns the connector of the transport and the address in the
+	// form the connector expects.
+	connect func(addr string) (srpc.Connector, string)
+}
+
+var transports = make(map[string]transport)
+
+func registerTransport(name string, t transport) {
+	if _, ok := transports[name]; ok {
+		panic(fmt.Sprintf("transport %q is already registered", name))
+	}
+	transports[name] = t
+}
+
+func transportNames() string {
+	return strings.Join(slices.Sorted(maps.Keys(transports)), ", ")
+}
+
+func init() {
+	var (
+		httpPath   string
+		httpMethod string
+	)
+	registerTransport("http", transport{
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&httpPath, "http-path", "/srpc", "path of the srpc endpoint (http)")
+			fs.StringVar(&httpMethod, "http-method", http.MethodPost, "method of the srpc endpoint (http)")
+		},
+		connect: func(addr string) (srpc.Connector, string) {
+			if !strings.Contains(addr, "://") {
+				addr = "http://" + addr
+			}
+			return httptransport.NewClientConnector(httpPath, httpMethod), addr
+		},
+	})
+
+	registerTransport("tcp", transport{
+		connect: func(addr string) (srpc.Connector, string) {
+			return tcptransport.NewClientConnector(), addr
+		},
+	})
+}
@@ -19,12 +19,36 @@ import (
 
 const version = "v0.0.1"
 
+const srpcPath = "github.com/tymbaca/srpc"
+
 type methodMeta struct {
 	Name     string
+	Kind     methodKind
 	ReqType  string
 	RespType string
 }
 
+type methodKind string
+
+const (
+	kindUnary        methodKind = "unary"
+	kindServerStream methodKind = "server-stream"
+	kindClientStream methodKind = "client-stream"
+	kindBidiStream   methodKind = "bidi-stream"
+)
+
+func (m methodMeta) IsServerStream() bool {
+	return m.Kind == kindServerStream
+}
+
+func (m methodMeta) IsClientStream() bool {
+	return m.Kind == kindClientStream
+}
+
+func (m methodMeta) IsBidiStream() bool {
+	return m.Kind == kindBidiStream
+}
+
 type importMeta struct {
 	Name string
 	Path string
@@ -45,6 +69,16 @@ type fileData struct {
 	Methods []methodMeta
 }
 
+// HasServerStreams reports whether the iter package is needed.
+func (d fileData) HasServerStreams() bool {
+	for _, m := range d.Methods {
+		if m.Kind == kindServerStream {
+			return true
+		}
+	}
+	return false
+}
+
 //go:embed srpc.client.go.tmpl
 var clientTmpl string
 
@@ -56,6 +90,7 @@ func main() {
 	only := flag.String("only", "", "generate only provided part: [client | server] (optional)")
 	clientOut := flag.String("client-out", "", "client filename (optional)")
 	serverOut := flag.String("server-out", "", "server filename (optional)")
+	protobuf := flag.Bool("protobuf", false, "require request and response types to be generated protobuf messages, for codec/protobuf (optional)")
 	flag.Parse()
 
 	if *target == "" {
@@ -66,14 +101,14 @@ func main() {
 	pkg := loadPackage(outDir)
 	iface := loadTargetInterface(pkg, *target)
 
-	methods, imports := collectMethods(pkg, iface)
+	methods, imports := collectMethods(pkg, iface, *protobuf)
 
 	generateFiles(pkg.Name, *target, outDir, methods, imports, *only, *clientOut, *serverOut)
 }
 
 func loadPackage(outDir string) *packages.Package {
 	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports,
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
 		Dir:  outDir,
 	}
 
@@ -183,7 +218,7 @@ func loadTargetInterface(pkg *packages.Package, target string) *types.Interface
 	return iface
 }
 
-func collectMethods(pkg *packages.Package, iface *types.Interface) ([]methodMeta, []importMeta) {
+func collectMethods(pkg *packages.Package, iface *types.Interface, protobuf bool) ([]methodMeta, []importMeta) {
 	qualifier := func(other *types.Package) string {
 		if other == nil || other.Path() == pkg.Types.Path() {
 			return ""
@@ -201,8 +236,8 @@ func collectMethods(pkg *packages.Package, iface *types.Interface) ([]methodMeta
 			failf("method %s has no signature", m.Name())
 		}
 
-		validateParams(m, sig)
-		methods = append(methods, buildMethodMeta(m, sig, qualifier, pkg, imports))
+		kind := validateParams(m, sig)
+		methods = append(methods, buildMethodMeta(m, sig, kind, protobuf, qualifier, pkg, imports))
 	}
 
 	var importMetas []importMeta
@@ -215,10 +250,10 @@ func collectMethods(pkg *packages.Package, iface *types.Interface) ([]methodMeta
 	return methods, importMetas
 }
 
-func validateParams(m *types.Func, sig *types.Signature) {
+func validateParams(m *types.Func, sig *types.Signature) methodKind {
 	params := sig.Params()
-	if params.Len() != 2 {
-		failf("method %s: expected 2 parameters, got %d", m.Name(), params.Len())
+	if params.Len() != 2 && params.Len() != 3 {
+		failf("method %s: expected 2 or 3 parameters, got %d", m.Name(), params.Len())
 	}
 
 	if params.At(0).Type().String() != "context.Context" {
@@ -226,6 +261,28 @@ func validateParams(m *types.Func, sig *types.Signature) {
 	}
 
 	results := sig.Results()
+	returnsErr := results.Len() == 1 && results.At(0).Type().String() == "error"
+
+	if params.Len() == 3 {
+		if _, ok := streamArgs(params.At(2).Type(), "ServerStream"); !ok {
+			failf("method %s: third parameter must be *srpc.ServerStream", m.Name())
+		}
+
+		if !returnsErr {
+			failf("method %s: server-streaming method must return only error", m.Name())
+		}
+
+		return kindServerStream
+	}
+
+	if _, ok := streamArgs(params.At(1).Type(), "BidiStream"); ok {
+		if !returnsErr {
+			failf("method %s: bidirectional streaming method must return only error", m.Name())
+		}
+
+		return kindBidiStream
+	}
+
 	if results.Len() != 2 {
 		failf("method %s: expected 2 results, got %d", m.Name(), results.Len())
 	}
@@ -233,26 +290,106 @@ func validateParams(m *types.Func, sig *types.Signature) {
 	if results.At(1).Type().String() != "error" {
 		failf("method %s: second result must be error", m.Name())
 	}
+
+	if _, ok := streamArgs(params.At(1).Type(), "ClientStream"); ok {
+		return kindClientStream
+	}
+
+	return kindUnary
+}
+
+// streamArgs returns the type arguments of *srpc.<name>[...].
+func streamArgs(t types.Type, name string) ([]types.Type, bool) {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return nil, false
+	}
+
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != srpcPath || named.Obj().Name() != name {
+		return nil, false
+	}
+
+	var args []types.Type
+	for i := range named.TypeArgs().Len() {
+		args = append(args, named.TypeArgs().At(i))
+	}
+
+	return args, true
 }
 
-func buildMethodMeta(m *types.Func, sig *types.Signature,
+func buildMethodMeta(m *types.Func, sig *types.Signature, kind methodKind, protobuf bool,
 	qualifier func(*types.Package) string, pkg *packages.Package,
 	imports map[string]string,
 ) methodMeta {
-	addImportIfExternal(sig.Params().At(1).Type(), pkg, imports)
-	addImportIfExternal(sig.Results().At(0).Type(), pkg, imports)
+	var reqType, respType types.Type
+	switch kind {
+	case kindServerStream:
+		args, _ := streamArgs(sig.Params().At(2).Type(), "ServerStream")
+		reqType, respType = sig.Params().At(1).Type(), args[0]
+	case kindClientStream:
+		args, _ := streamArgs(sig.Params().At(1).Type(), "ClientStream")
+		reqType, respType = args[0], sig.Results().At(0).Type()
+	case kindBidiStream:
+		args, _ := streamArgs(sig.Params().At(1).Type(), "BidiStream")
+		reqType, respType = args[0], args[1]
+	default:
+		reqType, respType = sig.Params().At(1).Type(), sig.Results().At(0).Type()
+	}
+
+	if protobuf {
+		for _, t := range []types.Type{reqType, respType} {
+			if !isProtoMessage(t) {
+				failf("method %s: %s is not a generated protobuf message (expected pointer to message, e.g. *pb.Req)", m.Name(), t)
+			}
+		}
+	}
+
+	addImportIfExternal(reqType, pkg, imports)
+	addImportIfExternal(respType, pkg, imports)
 
 	return methodMeta{
 		Name:     m.Name(),
-		ReqType:  types.TypeString(sig.Params().At(1).Type(), qualifier),
-		RespType: types.TypeString(sig.Results().At(0).Type(), qualifier),
+		Kind:     kind,
+		ReqType:  types.TypeString(reqType, qualifier),
+		RespType: types.TypeString(respType, qualifier),
 	}
 }
 
+// _protoReflectMessage is the result type of ProtoReflect method of the
+// generated protobuf messages.
+const _protoReflectMessage = "google.golang.org/protobuf/reflect/protoreflect.Message"
+
+// isProtoMessage reports whether t implements proto.Message, i.e. is the
+// pointer to the generated protobuf message.
+func isProtoMessage(t types.Type) bool {
+	if _, ok := t.(*types.Pointer); !ok {
+		return false
+	}
+
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "ProtoReflect")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+
+	sig := fn.Signature()
+	return sig.Params().Len() == 0 && sig.Results().Len() == 1 && sig.Results().At(0).Type().String() == _protoReflectMessage
+}
+
 func addImportIfExternal(t types.Type, pkg *packages.Package, imports map[string]string,
 ) {
+	// e.g. *pb.Req
+	for {
+		ptr, ok := t.(*types.Pointer)
+		if !ok {
+			break
+		}
+		t = ptr.Elem()
+	}
+
 	if named, ok := t.(*types.Named); ok {
-		if typePkg := named.Obj().Pkg(); typePkg != nil && typePkg.Path() != pkg.Types.Path() {
+		if typePkg := named.Obj().Pkg(); typePkg != nil && typePkg.Path() != pkg.Types.Path() && typePkg.Path() != srpcPath {
 			imports[typePkg.Name()] = typePkg.Path()
 		}
 	}
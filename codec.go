@@ -1,6 +1,10 @@
 package srpc
 
-import "io"
+import (
+	"io"
+	"reflect"
+	"sync"
+)
 
 type Codec interface {
 	Encoder
@@ -14,3 +18,72 @@ type Decoder interface {
 type Encoder interface {
 	Encode(w io.Writer, src any) error
 }
+
+// Marshaler is the optional interface of the [Encoder], which can encode the
+// value at once, appending the encoding to b. Complete bodies (i.e. not
+// streamed) are encoded with it into the pooled buffer, other encoders
+// write into the buffer with Encode.
+type Marshaler interface {
+	AppendMarshal(b []byte, src any) ([]byte, error)
+}
+
+// Unmarshaler is the optional interface of the [Decoder], which can decode
+// the value from its complete encoding. Complete bodies are read into the
+// pooled buffer and decoded with it, so data must not be retained after
+// Unmarshal returns.
+type Unmarshaler interface {
+	Unmarshal(data []byte, dst any) error
+}
+
+// ContentTypeKey is the metadata key of the request and response, which
+// names the codec of the body, see [RegisterCodec].
+const ContentTypeKey = "srpc-content-type"
+
+var codecs = struct {
+	sync.RWMutex
+	byName map[string]Codec
+	names  map[Codec]string // comparable codecs only
+}{
+	byName: make(map[string]Codec),
+	names:  make(map[Codec]string),
+}
+
+// RegisterCodec registers the codec under the content-type name, e.g.
+// "json". [Client] with the registered codec stamps its name into the
+// requests, and [Server] handles them with the codec of that name instead of
+// its own, so clients can be migrated to another codec one by one. Requests
+// without the name are handled with the codec of the server.
+//
+// It is meant to be called from init functions, codecs of this module are
+// registered by their packages.
+func RegisterCodec(name string, codec Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	codecs.byName[name] = codec
+	if reflect.ValueOf(codec).Comparable() {
+		codecs.names[codec] = name
+	}
+}
+
+// CodecByName returns the codec registered under the name.
+func CodecByName(name string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	codec, ok := codecs.byName[name]
+	return codec, ok
+}
+
+// codecName returns the name the codec is registered under.
+func codecName(codec Codec) (string, bool) {
+	if codec == nil || !reflect.ValueOf(codec).Comparable() {
+		return "", false
+	}
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	name, ok := codecs.names[codec]
+	return name, ok
+}
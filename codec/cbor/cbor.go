@@ -0,0 +1,36 @@
+// Package cbor provides the [srpc.Codec] for CBOR (RFC 8949):
+//
+//	server := srpc.NewServer(cbor.Codec)
+//	client := srpc.NewClient(addr, cbor.Codec, connector)
+//
+// Structs are encoded as maps keyed by field names. Names are taken from
+// cbor struct tags, falling back to json tags, so the types used with
+// [codec.JSON] can be used as is.
+package cbor
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+)
+
+var Codec srpc.Codec = cborCodec{
+	Codec:     codec.ToCodec(cbor.NewEncoder, cbor.NewDecoder),
+	Marshaler: codec.ToMarshaler(cbor.NewEncoder),
+}
+
+func init() {
+	srpc.RegisterCodec("cbor", Codec)
+}
+
+// cborCodec encodes the complete bodies with the pooled encoders and decodes
+// them with [cbor.Unmarshal], which needs no decoder with its own buffer, see
+// [srpc.Marshaler] and [srpc.Unmarshaler].
+type cborCodec struct {
+	srpc.Codec
+	srpc.Marshaler
+}
+
+func (cborCodec) Unmarshal(data []byte, dst any) error {
+	return cbor.Unmarshal(data, dst)
+}
@@ -0,0 +1,189 @@
+package codec_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/inmem"
+	"github.com/tymbaca/srpc/transport/testdata"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func roundTrip[T any](t *testing.T, codec srpc.Codec, src T) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, codec.Encode(&buf, src))
+
+	var dst T
+	require.NoError(t, codec.Decode(&buf, &dst))
+	require.Equal(t, src, dst)
+}
+
+func TestCodecs(t *testing.T) {
+	for _, c := range srpctest.Codecs {
+		t.Run(c.Name, func(t *testing.T) {
+			t.Run("round trip", func(t *testing.T) {
+				roundTrip(t, c.Codec, testdata.AddReq{A: 1, B: -2})
+				roundTrip(t, c.Codec, testdata.AddResp{Result: 1 << 40})
+				roundTrip(t, c.Codec, testdata.DivideReq{A: 10, B: 3})
+				roundTrip(t, c.Codec, testdata.DivideResp{Result: 3})
+				roundTrip(t, c.Codec, testdata.DivideByZero{A: 10})
+				roundTrip(t, c.Codec, testdata.CountReq{N: 5})
+				roundTrip(t, c.Codec, testdata.CountResp{I: 4})
+				roundTrip(t, c.Codec, testdata.SumReq{N: 1})
+				roundTrip(t, c.Codec, testdata.SumResp{Sum: 10})
+				roundTrip(t, c.Codec, testdata.EchoMsg{Text: "hello, мир"})
+			})
+
+			t.Run("json tags", func(t *testing.T) {
+				// the codec's own tag takes precedence over the json one
+				type tagged struct {
+					Name    string `json:"name"`
+					Skipped string `json:"-"`
+					Empty   string `json:"empty,omitempty"`
+					Own     string `json:"own" msgpack:"msgpack_own" cbor:"cbor_own"`
+				}
+				own := map[string]string{"json": "own", "msgpack": "msgpack_own", "cbor": "cbor_own"}[c.Name]
+
+				var buf bytes.Buffer
+				require.NoError(t, c.Codec.Encode(&buf, tagged{Name: "a", Skipped: "b", Own: "c"}))
+
+				var m map[string]any
+				require.NoError(t, c.Codec.Decode(bytes.NewReader(buf.Bytes()), &m))
+				require.Equal(t, map[string]any{"name": "a", own: "c"}, m)
+
+				roundTrip(t, c.Codec, tagged{Name: "a", Own: "c"})
+			})
+
+			t.Run("marshal", func(t *testing.T) {
+				m, ok := c.Codec.(srpc.Marshaler)
+				if !ok {
+					t.Skip("codec is not a marshaler")
+				}
+
+				src := testdata.EchoMsg{Text: "hello, мир"}
+				b, err := m.AppendMarshal([]byte("prefix"), src)
+				require.NoError(t, err)
+				require.True(t, bytes.HasPrefix(b, []byte("prefix")))
+
+				var dst testdata.EchoMsg
+				require.NoError(t, c.Codec.Decode(bytes.NewReader(b[len("prefix"):]), &dst))
+				require.Equal(t, src, dst)
+
+				b, err = m.AppendMarshal([]byte("prefix"), make(chan int))
+				require.Error(t, err)
+				require.Equal(t, []byte("prefix"), b)
+			})
+
+			t.Run("call", func(t *testing.T) {
+				testCall(t, c.Codec)
+			})
+		})
+	}
+}
+
+func testCall(t *testing.T, codec srpc.Codec) {
+	ctx := t.Context()
+	cluster := inmem.New()
+	serverPeer := cluster.NewPeer()
+
+	server := testdata.NewTestServiceServer(srpc.NewServer(codec))
+	t.Cleanup(func() { server.Close() })
+	go server.Start(ctx, serverPeer.Listen())
+
+	c := srpc.NewClient(serverPeer.Addr(), codec, cluster.NewPeer())
+	defer c.Close()
+	client := testdata.NewTestServiceClient(c)
+
+	resp, err := client.Add(ctx, testdata.AddReq{A: 10, B: 15})
+	require.NoError(t, err)
+	require.Equal(t, 25, resp.Result)
+
+	_, err = client.Divide(ctx, testdata.DivideReq{A: 1, B: 0})
+	detail, ok := srpc.ErrorDetail[testdata.DivideByZero](err)
+	require.True(t, ok)
+	require.Equal(t, 1, detail.A)
+
+	var got []int
+	for resp, err := range client.Count(ctx, testdata.CountReq{N: 3}) {
+		require.NoError(t, err)
+		got = append(got, resp.I)
+	}
+	require.Equal(t, []int{0, 1, 2}, got)
+
+	call := client.Echo(ctx)
+	require.NoError(t, call.Send(testdata.EchoMsg{Text: "a"}))
+	msg, err := call.Recv()
+	require.NoError(t, err)
+	require.Equal(t, "a", msg.Text)
+	require.NoError(t, call.CloseSend())
+	_, err = call.Recv()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+// BenchmarkCodecs compares encoding and decoding of the complete body with
+// the codec's Encoder and Decoder against [srpc.Marshaler] and
+// [srpc.Unmarshaler], which the buffered bodies use if implemented.
+func BenchmarkCodecs(b *testing.B) {
+	msg := testdata.EchoMsg{Text: "hello, мир"}
+
+	for _, c := range srpctest.Codecs {
+		var data bytes.Buffer
+		require.NoError(b, c.Codec.Encode(&data, msg))
+
+		b.Run(c.Name+"/encode", func(b *testing.B) {
+			var buf bytes.Buffer
+			b.ReportAllocs()
+			for b.Loop() {
+				buf.Reset()
+				if err := c.Codec.Encode(&buf, msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		if m, ok := c.Codec.(srpc.Marshaler); ok {
+			b.Run(c.Name+"/append_marshal", func(b *testing.B) {
+				var buf []byte
+				b.ReportAllocs()
+				for b.Loop() {
+					var err error
+					if buf, err = m.AppendMarshal(buf[:0], msg); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+
+		b.Run(c.Name+"/decode", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				var dst testdata.EchoMsg
+				if err := c.Codec.Decode(bytes.NewReader(data.Bytes()), &dst); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		if u, ok := c.Codec.(srpc.Unmarshaler); ok {
+			b.Run(c.Name+"/unmarshal", func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					var dst testdata.EchoMsg
+					if err := u.Unmarshal(data.Bytes(), &dst); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package codec
 
 import (
+	"bytes"
 	"io"
+	"sync"
 
 	"github.com/tymbaca/srpc"
 )
@@ -23,3 +25,47 @@ type commonEncoder interface {
 func (co *commonEncoderWrapper[T]) Encode(w io.Writer, src any) error {
 	return co.newFunc(w).Encode(src)
 }
+
+// _maxPooledEncoderSize is the capacity of the encoder's buffer, above which
+// the encoder is dropped instead of being returned to the pool.
+const _maxPooledEncoderSize = 64 << 10
+
+// ToMarshaler returns the [srpc.Marshaler], which encodes with the pooled
+// encoders of newFunc, each writing into its own buffer, so neither the
+// encoder nor the encoding is allocated per call.
+func ToMarshaler[T commonEncoder](newFunc NewEncoderFunc[T]) srpc.Marshaler {
+	m := &pooledMarshaler[T]{}
+	m.pool.New = func() any {
+		e := &pooledEncoder[T]{}
+		e.enc = newFunc(&e.buf)
+		return e
+	}
+
+	return m
+}
+
+type pooledMarshaler[T commonEncoder] struct {
+	pool sync.Pool
+}
+
+type pooledEncoder[T commonEncoder] struct {
+	buf bytes.Buffer
+	enc T
+}
+
+func (m *pooledMarshaler[T]) AppendMarshal(b []byte, src any) ([]byte, error) {
+	e := m.pool.Get().(*pooledEncoder[T])
+	e.buf.Reset()
+
+	// the encoder is dropped on error, its state is unknown
+	if err := e.enc.Encode(src); err != nil {
+		return b, err
+	}
+	b = append(b, e.buf.Bytes()...)
+
+	if e.buf.Cap() <= _maxPooledEncoderSize {
+		m.pool.Put(e)
+	}
+
+	return b, nil
+}
@@ -1,5 +1,28 @@
 package codec
 
-import "encoding/json"
+import (
+	"encoding/json"
 
-var JSON = ToCodec(json.NewEncoder, json.NewDecoder)
+	"github.com/tymbaca/srpc"
+)
+
+var JSON srpc.Codec = jsonCodec{
+	Codec:     ToCodec(json.NewEncoder, json.NewDecoder),
+	Marshaler: ToMarshaler(json.NewEncoder),
+}
+
+func init() {
+	srpc.RegisterCodec("json", JSON)
+}
+
+// jsonCodec encodes the complete bodies with the pooled encoders and decodes
+// them with [json.Unmarshal], which needs no decoder with its own buffer,
+// see [srpc.Marshaler] and [srpc.Unmarshaler].
+type jsonCodec struct {
+	srpc.Codec
+	srpc.Marshaler
+}
+
+func (jsonCodec) Unmarshal(data []byte, dst any) error {
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,49 @@
+// Package msgpack provides the [srpc.Codec] for MessagePack:
+//
+//	server := srpc.NewServer(msgpack.Codec)
+//	client := srpc.NewClient(addr, msgpack.Codec, connector)
+//
+// Structs are encoded as maps keyed by field names. Names are taken from
+// msgpack struct tags, falling back to json tags, so the types used with
+// codec.JSON can be used as is.
+package msgpack
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/tymbaca/srpc"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes and decodes the values with the pooled encoders and decoders
+// of msgpack.
+var Codec srpc.Codec = msgpackCodec{}
+
+func init() {
+	srpc.RegisterCodec("msgpack", Codec)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Encode(w io.Writer, src any) error {
+	enc := msgpack.GetEncoder()
+	defer msgpack.PutEncoder(enc)
+
+	enc.Reset(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(src)
+}
+
+func (msgpackCodec) Decode(r io.Reader, dst any) error {
+	dec := msgpack.GetDecoder()
+	defer msgpack.PutDecoder(dec)
+
+	dec.Reset(r)
+	dec.SetCustomStructTag("json")
+	return dec.Decode(dst)
+}
+
+func (c msgpackCodec) Unmarshal(data []byte, dst any) error {
+	return c.Decode(bytes.NewReader(data), dst)
+}
@@ -0,0 +1,105 @@
+// Package protobuf provides the [srpc.Codec] for Protocol Buffers:
+//
+//	server := srpc.NewServer(protobuf.Codec)
+//	client := srpc.NewClient(addr, protobuf.Codec, connector)
+//
+// Request and response types of the methods must be generated protobuf
+// messages, passed by pointer (e.g. *pb.AddReq), see srpc-gen -protobuf.
+// Other values fail with [ErrNotMessage].
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/tymbaca/srpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes and decodes [proto.Message] values in protobuf wire format.
+var Codec srpc.Codec = protoCodec{}
+
+func init() {
+	srpc.RegisterCodec("protobuf", Codec)
+}
+
+// ErrNotMessage is returned if the value to encode or decode is not a
+// [proto.Message].
+var ErrNotMessage = errors.New("not a proto.Message")
+
+var messageType = reflect.TypeFor[proto.Message]()
+
+type protoCodec struct{}
+
+func (c protoCodec) Encode(w io.Writer, src any) error {
+	data, err := c.AppendMarshal(nil, src)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// AppendMarshal appends the encoding of src to b, see [srpc.Marshaler].
+func (protoCodec) AppendMarshal(b []byte, src any) ([]byte, error) {
+	m, ok := src.(proto.Message)
+	if !ok {
+		return b, fmt.Errorf("protobuf: encode %T: %w", src, ErrNotMessage)
+	}
+
+	b, err := proto.MarshalOptions{}.MarshalAppend(b, m)
+	if err != nil {
+		return b, fmt.Errorf("protobuf: encode %T: %w", src, err)
+	}
+
+	return b, nil
+}
+
+func (c protoCodec) Decode(r io.Reader, dst any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return c.Unmarshal(data, dst)
+}
+
+// Unmarshal decodes data into dst, see [srpc.Unmarshaler].
+func (protoCodec) Unmarshal(data []byte, dst any) error {
+	m, err := message(dst)
+	if err != nil {
+		return err
+	}
+
+	if err := proto.Unmarshal(data, m); err != nil {
+		return fmt.Errorf("protobuf: decode %T: %w", dst, err)
+	}
+
+	return nil
+}
+
+// message returns the message to decode into: dst itself, or the message
+// *dst points to, if dst is a pointer to the message pointer (e.g.
+// **pb.AddResp, as the responses are decoded into &resp). Nil *dst is set to
+// the new message, nil dst is an error.
+func message(dst any) (proto.Message, error) {
+	if m, ok := dst.(proto.Message); ok && m.ProtoReflect().IsValid() {
+		return m, nil
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		elem := v.Elem()
+		if elem.Kind() == reflect.Pointer && elem.Type().Implements(messageType) {
+			if elem.IsNil() {
+				elem.Set(reflect.New(elem.Type().Elem()))
+			}
+			return elem.Interface().(proto.Message), nil
+		}
+	}
+
+	return nil, fmt.Errorf("protobuf: decode into %T: %w", dst, ErrNotMessage)
+}
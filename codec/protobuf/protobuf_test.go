@@ -0,0 +1,111 @@
+package protobuf
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/transport/inmem"
+	"go.uber.org/goleak"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := timestamppb.New(time.Date(2024, 5, 1, 12, 0, 0, 42, time.UTC))
+
+	var buf bytes.Buffer
+	require.NoError(t, Codec.Encode(&buf, src))
+
+	t.Run("message", func(t *testing.T) {
+		dst := &timestamppb.Timestamp{}
+		require.NoError(t, Codec.Decode(bytes.NewReader(buf.Bytes()), dst))
+		require.True(t, proto.Equal(src, dst))
+	})
+
+	t.Run("pointer to nil message", func(t *testing.T) {
+		var dst *timestamppb.Timestamp
+		require.NoError(t, Codec.Decode(bytes.NewReader(buf.Bytes()), &dst))
+		require.True(t, proto.Equal(src, dst))
+	})
+}
+
+func TestNotMessage(t *testing.T) {
+	type plain struct{ A int }
+
+	require.ErrorIs(t, Codec.Encode(&bytes.Buffer{}, plain{A: 1}), ErrNotMessage)
+	require.ErrorIs(t, Codec.Decode(bytes.NewReader(nil), &plain{}), ErrNotMessage)
+	require.ErrorIs(t, Codec.Decode(bytes.NewReader(nil), (*timestamppb.Timestamp)(nil)), ErrNotMessage)
+}
+
+type greeter struct{}
+
+func (greeter) Greet(ctx context.Context, req *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	if req.GetValue() == "" {
+		return nil, srpc.Errorf(srpc.CodeInvalidArgument, "name is required")
+	}
+	return wrapperspb.String("hello, " + req.GetValue()), nil
+}
+
+func (greeter) Count(ctx context.Context, req *wrapperspb.Int32Value, stream *srpc.ServerStream[*wrapperspb.Int32Value]) error {
+	for i := range req.GetValue() {
+		if err := stream.Send(wrapperspb.Int32(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCall(t *testing.T) {
+	ctx := t.Context()
+	cluster := inmem.New()
+	serverPeer := cluster.NewPeer()
+
+	server := srpc.NewServer(Codec)
+	srpc.RegisterWithName(server, greeter{}, "Greeter")
+	t.Cleanup(func() { server.Close() })
+	go server.Start(ctx, serverPeer.Listen())
+
+	client := srpc.NewClient(serverPeer.Addr(), Codec, cluster.NewPeer())
+	defer client.Close()
+
+	t.Run("unary", func(t *testing.T) {
+		var resp *wrapperspb.StringValue
+		require.NoError(t, client.Call(ctx, "Greeter.Greet", wrapperspb.String("world"), &resp))
+		require.Equal(t, "hello, world", resp.GetValue())
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		var resp *wrapperspb.StringValue
+		err := client.Call(ctx, "Greeter.Greet", wrapperspb.String(""), &resp)
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(err))
+	})
+
+	t.Run("server stream", func(t *testing.T) {
+		var got []int32
+		for msg, err := range srpc.CallServerStream[*wrapperspb.Int32Value](ctx, client, "Greeter.Count", wrapperspb.Int32(3)) {
+			require.NoError(t, err)
+			got = append(got, msg.GetValue())
+		}
+		require.Equal(t, []int32{0, 1, 2}, got)
+	})
+
+	t.Run("not a message", func(t *testing.T) {
+		var resp *wrapperspb.StringValue
+		err := client.Call(ctx, "Greeter.Greet", "world", &resp)
+		require.ErrorIs(t, err, ErrNotMessage)
+
+		var plain string
+		err = client.Call(ctx, "Greeter.Greet", wrapperspb.String("world"), &plain)
+		require.ErrorIs(t, err, ErrNotMessage)
+	})
+}
@@ -0,0 +1,84 @@
+package srpc_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/codec/cbor"
+	"github.com/tymbaca/srpc/codec/msgpack"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/testdata"
+)
+
+// optsCodec is comparable by type, but not by value if opts holds a slice.
+type optsCodec struct {
+	srpc.Codec
+	opts any
+}
+
+func TestCodecNegotiation(t *testing.T) {
+	ctx := t.Context()
+	server := srpctest.StartTestService(t)
+
+	newClient := func(t *testing.T, c srpc.Codec) *testdata.TestServiceClient {
+		client := srpc.NewClient(server.Addr, c, server.Cluster.NewPeer())
+		t.Cleanup(func() { client.Close() })
+		return testdata.NewTestServiceClient(client)
+	}
+
+	t.Run("registered codecs", func(t *testing.T) {
+		for name, c := range map[string]srpc.Codec{"json": codec.JSON, "msgpack": msgpack.Codec, "cbor": cbor.Codec} {
+			registered, ok := srpc.CodecByName(name)
+			require.True(t, ok)
+			require.Equal(t, c, registered)
+
+			var header srpc.Metadata
+			resp, err := newClient(t, c).Add(ctx, testdata.AddReq{A: 1, B: 2}, srpc.Header(&header))
+			require.NoError(t, err)
+			require.Equal(t, 3, resp.Result)
+			require.Equal(t, []string{name}, header.Get(srpc.ContentTypeKey))
+
+			var got []int
+			for resp, err := range newClient(t, c).Count(ctx, testdata.CountReq{N: 3}) {
+				require.NoError(t, err)
+				got = append(got, resp.I)
+			}
+			require.Equal(t, []int{0, 1, 2}, got)
+		}
+	})
+
+	t.Run("unregistered codec uses server codec", func(t *testing.T) {
+		var header srpc.Metadata
+		resp, err := newClient(t, codec.ToCodec(json.NewEncoder, json.NewDecoder)).Add(ctx, testdata.AddReq{A: 1, B: 2}, srpc.Header(&header))
+		require.NoError(t, err)
+		require.Equal(t, 3, resp.Result)
+		require.Empty(t, header.Get(srpc.ContentTypeKey))
+	})
+
+	t.Run("unsupported codec", func(t *testing.T) {
+		ctx := srpc.AppendToOutgoingContext(ctx, srpc.ContentTypeKey, "yaml")
+		_, err := newClient(t, codec.ToCodec(json.NewEncoder, json.NewDecoder)).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.ErrorIs(t, err, srpc.ErrTransportError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(err))
+		require.ErrorContains(t, err, `unsupported codec "yaml"`)
+	})
+
+	t.Run("incomparable codec", func(t *testing.T) {
+		c := optsCodec{Codec: codec.ToCodec(json.NewEncoder, json.NewDecoder), opts: []string{"indent"}}
+		srpc.RegisterCodec("json-opts", c)
+
+		registered, ok := srpc.CodecByName("json-opts")
+		require.True(t, ok)
+		require.Equal(t, c, registered)
+
+		// the name of the codec can't be looked up, so the server codec is used
+		var header srpc.Metadata
+		resp, err := newClient(t, c).Add(ctx, testdata.AddReq{A: 1, B: 2}, srpc.Header(&header))
+		require.NoError(t, err)
+		require.Equal(t, 3, resp.Result)
+		require.Empty(t, header.Get(srpc.ContentTypeKey))
+	})
+}
@@ -0,0 +1,226 @@
+package srpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/tymbaca/srpc/pkg/pipe"
+)
+
+// Compressor compresses the bodies of the calls, see [RegisterCompressor].
+type Compressor interface {
+	// Compress returns the writer, which compresses the data into w. Close
+	// must write the rest of the data. If the writer has Flush() error
+	// method, it is called after every chunk of the streamed body, so the
+	// messages are not held back.
+	Compress(w io.Writer) (io.WriteCloser, error)
+
+	// Decompress returns the reader of the data decompressed from r.
+	Decompress(r io.Reader) (io.Reader, error)
+}
+
+const (
+	// EncodingKey is the metadata key of the request and response, which
+	// names the compressor of the body.
+	EncodingKey = "srpc-encoding"
+
+	// AcceptEncodingKey is the metadata key of the request, which lists the
+	// compressors the client can decompress the response with.
+	AcceptEncodingKey = "srpc-accept-encoding"
+)
+
+var compressors sync.Map // name -> Compressor
+
+// RegisterCompressor registers the compressor under the name, e.g. "gzip".
+// Compressed bodies are decompressed by both [Client] and [Server] with the
+// compressor of the name they are sent with, see [EncodingKey].
+//
+// It is meant to be called from init functions, compressors of this module
+// are registered by their packages.
+func RegisterCompressor(name string, c Compressor) {
+	compressors.Store(name, c)
+}
+
+// CompressorByName returns the compressor registered under the name.
+func CompressorByName(name string) (Compressor, bool) {
+	c, ok := compressors.Load(name)
+	if !ok {
+		return nil, false
+	}
+
+	return c.(Compressor), true
+}
+
+// compressorNames returns the sorted names of the registered compressors.
+func compressorNames() []string {
+	var names []string
+	compressors.Range(func(name, _ any) bool {
+		names = append(names, name.(string))
+		return true
+	})
+	slices.Sort(names)
+
+	return names
+}
+
+// CompressionConfig configures the compression of the bodies.
+//
+// Client with the config compresses the requests with Compressor and
+// accepts the responses compressed with any registered compressor. Server
+// compresses the responses with Compressor, if the client accepts it, or
+// with the compressor of the request, so it needs no config to reply to the
+// compressed requests in kind.
+type CompressionConfig struct {
+	// Compressor is the name of the registered compressor, e.g. "gzip".
+	// Empty client's Compressor means the requests are sent as is, but the
+	// compressed responses are accepted.
+	Compressor string
+
+	// MinSize is the minimum size of the body to compress, smaller bodies
+	// are sent as is. Streamed bodies are compressed regardless of the size.
+	// Zero means [DefaultCompressionMinSize].
+	MinSize int
+
+	// DisabledMethods are the methods, which bodies are not compressed, e.g.
+	// ones with already compressed payload.
+	DisabledMethods []ServiceMethod
+}
+
+const DefaultCompressionMinSize = 1 << 10
+
+func (cfg CompressionConfig) minSize() int {
+	return tern(cfg.MinSize > 0, cfg.MinSize, DefaultCompressionMinSize)
+}
+
+func (cfg CompressionConfig) disabled(serviceMethod ServiceMethod) bool {
+	return slices.Contains(cfg.DisabledMethods, serviceMethod)
+}
+
+// compressBody returns the body compressed with c. Complete body (i.e. not
+// streamed), which is smaller than minSize, is returned as is, reporting
+// false. The error is returned if the body fails before minSize, e.g. it
+// can't be encoded.
+func compressBody(body io.ReadCloser, c Compressor, minSize int, complete bool) (io.ReadCloser, bool, error) {
+	src := io.Reader(body)
+	if complete {
+		if b, ok := body.(*bufferBody); ok {
+			if b.Len() < minSize {
+				return body, false, nil
+			}
+		} else {
+			head := getBuffer()
+			_, err := head.ReadFrom(io.LimitReader(body, int64(minSize)))
+			if err != nil {
+				putBuffer(head)
+				return body, false, err
+			}
+
+			headBody := &bufferBody{buf: head}
+			if head.Len() < minSize {
+				return readCloser{Reader: headBody, Closer: closers{headBody, body}}, false, nil
+			}
+
+			src = io.MultiReader(headBody, body)
+			body = readCloser{Reader: body, Closer: closers{headBody, body}}
+		}
+	}
+
+	compressed := pipe.ToReader(func(w io.Writer) error {
+		return compressTo(w, src, c, !complete)
+	})
+
+	return readCloser{Reader: compressed, Closer: closers{compressed, body}}, true, nil
+}
+
+// compressTo compresses everything from r into w. If flush is set, every
+// chunk read from r is flushed to w.
+func compressTo(w io.Writer, r io.Reader, c Compressor, flush bool) error {
+	zw, err := c.Compress(w)
+	if err != nil {
+		return fmt.Errorf("create compressor: %w", err)
+	}
+
+	flusher, canFlush := zw.(interface{ Flush() error })
+	flush = flush && canFlush
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if _, err := zw.Write(buf[:n]); err != nil {
+				return err
+			}
+			if flush {
+				if err := flusher.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			return zw.Close()
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// decompressBody returns the body decompressed with the compressor named by
+// [EncodingKey] of md.
+func decompressBody(body io.Reader, md Metadata) (io.Reader, error) {
+	enc := md.Get(EncodingKey)
+	if len(enc) == 0 || enc[0] == "" || enc[0] == "identity" {
+		return body, nil
+	}
+
+	c, ok := CompressorByName(enc[0])
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q", enc[0])
+	}
+
+	return &decompressReader{src: body, c: c}, nil
+}
+
+// decompressReader creates the decompressing reader on the first Read, as
+// it may read the body right away (e.g. the gzip header), while the
+// streamed body is yet to be written.
+type decompressReader struct {
+	src io.Reader
+	c   Compressor
+	r   io.Reader
+	err error
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		d.r, d.err = d.c.Decompress(d.src)
+		if d.err != nil {
+			d.err = fmt.Errorf("decompress body: %w", d.err)
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	return d.r.Read(p)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var errs []error
+	for _, c := range cs {
+		errs = append(errs, c.Close())
+	}
+
+	return errors.Join(errs...)
+}
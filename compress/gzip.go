@@ -0,0 +1,34 @@
+// Package compress provides the [srpc.Compressor] implementations. Gzip is
+// provided by this package, the others are in the subpackages, so their
+// dependencies are pulled only if used:
+//
+//	client := srpc.NewClient(addr, codec.JSON, connector, srpc.WithClientCompression(srpc.CompressionConfig{
+//		Compressor: "gzip",
+//	}))
+//
+// Compressors are registered by the packages, so they must be imported by
+// both the client and the server.
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/tymbaca/srpc"
+)
+
+var Gzip srpc.Compressor = gzipCompressor{}
+
+func init() {
+	srpc.RegisterCompressor("gzip", Gzip)
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
@@ -0,0 +1,26 @@
+// Package snappy provides the [srpc.Compressor] for Snappy framing format,
+// registered as "snappy".
+package snappy
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/tymbaca/srpc"
+)
+
+var Compressor srpc.Compressor = snappyCompressor{}
+
+func init() {
+	srpc.RegisterCompressor("snappy", Compressor)
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return snappy.NewBufferedWriter(w), nil
+}
+
+func (snappyCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return snappy.NewReader(r), nil
+}
@@ -0,0 +1,28 @@
+// Package zstd provides the [srpc.Compressor] for Zstandard, registered as
+// "zstd".
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/tymbaca/srpc"
+)
+
+var Compressor srpc.Compressor = zstdCompressor{}
+
+func init() {
+	srpc.RegisterCompressor("zstd", Compressor)
+}
+
+// zstdCompressor encodes and decodes synchronously, so no background
+// goroutines are left behind, if the body is abandoned.
+type zstdCompressor struct{}
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+}
@@ -0,0 +1,183 @@
+package srpc_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	_ "github.com/tymbaca/srpc/compress"
+	_ "github.com/tymbaca/srpc/compress/snappy"
+	_ "github.com/tymbaca/srpc/compress/zstd"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/testdata"
+)
+
+// recordEncoding returns the interceptor, which records the encoding of the
+// requests, and the function returning the one of the last request.
+func recordEncoding() (srpc.UnaryServerInterceptor, func() string) {
+	var (
+		mu      sync.Mutex
+		lastEnc string
+	)
+	record := func(ctx context.Context, req srpc.Request, next srpc.UnaryHandler) srpc.Response {
+		mu.Lock()
+		lastEnc = strings.Join(req.Metadata.Get(srpc.EncodingKey), ",")
+		mu.Unlock()
+		return next(ctx, req)
+	}
+
+	return record, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return lastEnc
+	}
+}
+
+func TestCompression(t *testing.T) {
+	ctx := t.Context()
+
+	for _, name := range []string{"gzip", "zstd", "snappy"} {
+		t.Run(name, func(t *testing.T) {
+			// the server replies with the compressor of the request
+			record, reqEnc := recordEncoding()
+			server := srpctest.StartTestService(t, srpc.WithServerCompression(srpc.CompressionConfig{MinSize: 1}), srpc.WithUnaryServerInterceptors(record))
+			c := server.Client(t, srpc.WithClientCompression(srpc.CompressionConfig{
+				Compressor: name,
+				MinSize:    1,
+			}))
+			client := testdata.NewTestServiceClient(c)
+
+			var header srpc.Metadata
+			resp, err := client.Add(ctx, testdata.AddReq{A: 1, B: 2}, srpc.Header(&header))
+			require.NoError(t, err)
+			require.Equal(t, 3, resp.Result)
+			require.Equal(t, name, reqEnc())
+			require.Equal(t, []string{name}, header.Get(srpc.EncodingKey))
+
+			var got []int
+			for resp, err := range client.Count(ctx, testdata.CountReq{N: 3}) {
+				require.NoError(t, err)
+				got = append(got, resp.I)
+			}
+			require.Equal(t, []int{0, 1, 2}, got)
+
+			// streamed messages are not held back by the compressor
+			call := client.Echo(ctx)
+			for _, text := range []string{"a", "b"} {
+				require.NoError(t, call.Send(testdata.EchoMsg{Text: text}))
+				msg, err := call.Recv()
+				require.NoError(t, err)
+				require.Equal(t, text, msg.Text)
+			}
+			require.NoError(t, call.CloseSend())
+			_, err = call.Recv()
+			require.ErrorIs(t, err, io.EOF)
+		})
+	}
+
+	t.Run("min size", func(t *testing.T) {
+		record, reqEnc := recordEncoding()
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(record))
+		c := server.Client(t, srpc.WithClientCompression(srpc.CompressionConfig{Compressor: "gzip"}))
+
+		var header srpc.Metadata
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2}, srpc.Header(&header))
+		require.NoError(t, err)
+		require.Empty(t, reqEnc())
+		require.Empty(t, header.Get(srpc.EncodingKey))
+	})
+
+	t.Run("disabled method", func(t *testing.T) {
+		record, reqEnc := recordEncoding()
+		server := srpctest.StartTestService(t, srpc.WithServerCompression(srpc.CompressionConfig{Compressor: "gzip", MinSize: 1}), srpc.WithUnaryServerInterceptors(record))
+		c := server.Client(t, srpc.WithClientCompression(srpc.CompressionConfig{
+			Compressor:      "gzip",
+			MinSize:         1,
+			DisabledMethods: []srpc.ServiceMethod{"TestService.Add"},
+		}))
+
+		var header srpc.Metadata
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2}, srpc.Header(&header))
+		require.NoError(t, err)
+		require.Empty(t, reqEnc())
+		require.Empty(t, header.Get(srpc.EncodingKey))
+	})
+
+	t.Run("server compressor", func(t *testing.T) {
+		record, reqEnc := recordEncoding()
+		server := srpctest.StartTestService(t, srpc.WithServerCompression(srpc.CompressionConfig{Compressor: "zstd", MinSize: 1}), srpc.WithUnaryServerInterceptors(record))
+
+		// the client accepts compressed responses, but doesn't compress
+		// the requests
+		c := server.Client(t, srpc.WithClientCompression(srpc.CompressionConfig{}))
+
+		var header srpc.Metadata
+		resp, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2}, srpc.Header(&header))
+		require.NoError(t, err)
+		require.Equal(t, 3, resp.Result)
+		require.Empty(t, reqEnc())
+		require.Equal(t, []string{"zstd"}, header.Get(srpc.EncodingKey))
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		server := srpctest.StartTestService(t, srpc.WithServerCompression(srpc.CompressionConfig{Compressor: "gzip", MinSize: 1}))
+		c := server.Client(t)
+
+		var header srpc.Metadata
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2}, srpc.Header(&header))
+		require.NoError(t, err)
+		require.Empty(t, header.Get(srpc.EncodingKey))
+	})
+
+	t.Run("replayed body", func(t *testing.T) {
+		record, reqEnc := recordEncoding()
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(record))
+
+		// the interceptor sends the replayed body, as the transport does
+		// when it resends the request
+		replay := func(ctx context.Context, req srpc.Request, invoke srpc.UnaryInvoker) (srpc.Response, error) {
+			if _, err := io.Copy(io.Discard, req.Body); err != nil {
+				return srpc.Response{}, err
+			}
+
+			body, err := req.GetBody()
+			if err != nil {
+				return srpc.Response{}, err
+			}
+			req.Body = body
+
+			return invoke(ctx, req)
+		}
+		c := server.Client(t,
+			srpc.WithClientCompression(srpc.CompressionConfig{Compressor: "gzip", MinSize: 1}),
+			srpc.WithUnaryClientInterceptors(replay),
+		)
+
+		resp, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.NoError(t, err)
+		require.Equal(t, 3, resp.Result)
+		require.Equal(t, "gzip", reqEnc())
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		server := srpctest.StartTestService(t)
+		c := server.Client(t)
+
+		ctx := srpc.AppendToOutgoingContext(ctx, srpc.EncodingKey, "lz4")
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(err))
+		require.ErrorContains(t, err, `unsupported encoding "lz4"`)
+	})
+
+	t.Run("unregistered compressor", func(t *testing.T) {
+		server := srpctest.StartTestService(t)
+		c := server.Client(t, srpc.WithClientCompression(srpc.CompressionConfig{Compressor: "lz4"}))
+
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.ErrorContains(t, err, `compressor "lz4" is not registered`)
+	})
+}
@@ -0,0 +1,53 @@
+package srpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutKey is the metadata key of the time, which the client is ready to
+// wait for the response. [Client] sets it from the deadline of the call
+// context, so the handler on the server side stops once the client gives
+// up. The value is formatted with [time.Duration.String].
+const TimeoutKey = "srpc-timeout"
+
+// errDeadlineExceeded is the cause of the handler context cancellation,
+// once the deadline of the client is exceeded.
+var errDeadlineExceeded = Errorf(CodeDeadlineExceeded, "deadline of the call is exceeded")
+
+// setTimeout returns md with the time left until the deadline of ctx, if
+// any. md is copied, so it can be reused by the caller.
+func setTimeout(ctx context.Context, md Metadata) Metadata {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return md
+	}
+
+	md = md.Copy()
+	md.Set(TimeoutKey, time.Until(deadline).String())
+
+	return md
+}
+
+// parseTimeout returns the timeout of the request, if the client has set it.
+func parseTimeout(md Metadata) (time.Duration, bool, error) {
+	vals := md.Get(TimeoutKey)
+	if len(vals) == 0 {
+		return 0, false, nil
+	}
+
+	timeout, err := time.ParseDuration(vals[0])
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid %s: %w", TimeoutKey, err)
+	}
+
+	return timeout, true, nil
+}
+
+// deadlineExceeded reports whether ctx is cancelled, because the deadline of
+// the client is exceeded.
+func deadlineExceeded(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errDeadlineExceeded)
+}
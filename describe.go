@@ -0,0 +1,145 @@
+package srpc
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// ServiceDesc describes the service registered on the [Server].
+type ServiceDesc struct {
+	Name    string       `json:"name"`
+	Methods []MethodDesc `json:"methods"`
+}
+
+// MethodDesc describes the method of the service. For streaming methods
+// Request and Response describe the streamed messages.
+type MethodDesc struct {
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"` // unary, server-stream, client-stream or bidi-stream
+	Request  TypeDesc `json:"request"`
+	Response TypeDesc `json:"response"`
+}
+
+// TypeDesc is the schema of the message type, derived from its Go type.
+// Named types are described once per message, repeated (e.g. recursive)
+// occurrences have only Name and Kind.
+type TypeDesc struct {
+	Name   string      `json:"name,omitempty"` // Go type name with package path, if the type is named
+	Kind   string      `json:"kind"`           // reflect.Kind, e.g. struct, slice, string
+	Fields []FieldDesc `json:"fields,omitempty"`
+	Key    *TypeDesc   `json:"key,omitempty"`  // key of map
+	Elem   *TypeDesc   `json:"elem,omitempty"` // element of slice, array, map or pointer
+}
+
+// FieldDesc describes the exported field of the struct.
+type FieldDesc struct {
+	Name string   `json:"name"`
+	Tag  string   `json:"tag,omitempty"`
+	Type TypeDesc `json:"type"`
+}
+
+func (k methodKind) String() string {
+	switch k {
+	case methodUnary:
+		return "unary"
+	case methodServerStream:
+		return "server-stream"
+	case methodClientStream:
+		return "client-stream"
+	case methodBidiStream:
+		return "bidi-stream"
+	}
+
+	return ""
+}
+
+// Services describes the services registered on the server, sorted by name.
+func (s *Server) Services() []ServiceDesc {
+	descs := make([]ServiceDesc, 0, len(s.services))
+	for _, name := range slices.Sorted(maps.Keys(s.services)) {
+		descs = append(descs, s.services[name].describe())
+	}
+
+	return descs
+}
+
+func (svc service) describe() ServiceDesc {
+	desc := ServiceDesc{Name: svc.name, Methods: []MethodDesc{}}
+	for _, name := range slices.Sorted(maps.Keys(svc.methods)) {
+		m := svc.methods[name]
+		req, resp := m.messageTypes()
+		desc.Methods = append(desc.Methods, MethodDesc{
+			Name:     name,
+			Kind:     m.kind.String(),
+			Request:  describeType(req, map[reflect.Type]bool{}),
+			Response: describeType(resp, map[reflect.Type]bool{}),
+		})
+	}
+
+	return desc
+}
+
+// messageTypes returns the types of request and response messages of the
+// method.
+func (m method) messageTypes() (req, resp reflect.Type) {
+	typ := m.val.Type()
+
+	// streamed message type is taken from the methods of the stream, e.g.
+	// Send(msg T)
+	sendType := func(st reflect.Type) reflect.Type {
+		send, _ := st.MethodByName("Send")
+		return send.Type.In(1)
+	}
+	recvType := func(st reflect.Type) reflect.Type {
+		recv, _ := st.MethodByName("Recv")
+		return recv.Type.Out(0)
+	}
+
+	switch m.kind {
+	case methodServerStream:
+		return typ.In(1), sendType(typ.In(2))
+	case methodClientStream:
+		return recvType(typ.In(1)), typ.Out(0)
+	case methodBidiStream:
+		return recvType(typ.In(1)), sendType(typ.In(1))
+	}
+
+	return typ.In(1), typ.Out(0)
+}
+
+func describeType(t reflect.Type, seen map[reflect.Type]bool) TypeDesc {
+	desc := TypeDesc{Kind: t.Kind().String()}
+	if t.Name() != "" {
+		desc.Name = typeName(t)
+		if seen[t] {
+			return desc
+		}
+		seen[t] = true
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		desc.Fields = []FieldDesc{}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			desc.Fields = append(desc.Fields, FieldDesc{
+				Name: f.Name,
+				Tag:  string(f.Tag),
+				Type: describeType(f.Type, seen),
+			})
+		}
+	case reflect.Map:
+		key := describeType(t.Key(), seen)
+		desc.Key = &key
+		fallthrough
+	case reflect.Slice, reflect.Array, reflect.Pointer:
+		elem := describeType(t.Elem(), seen)
+		desc.Elem = &elem
+	}
+
+	return desc
+}
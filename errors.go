@@ -0,0 +1,223 @@
+package srpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Code is a stable code of the [Error], clients can branch on.
+type Code uint32
+
+const (
+	CodeOK                 Code = 0
+	CodeCanceled           Code = 1
+	CodeUnknown            Code = 2
+	CodeInvalidArgument    Code = 3
+	CodeDeadlineExceeded   Code = 4
+	CodeNotFound           Code = 5
+	CodeAlreadyExists      Code = 6
+	CodePermissionDenied   Code = 7
+	CodeResourceExhausted  Code = 8
+	CodeFailedPrecondition Code = 9
+	CodeAborted            Code = 10
+	CodeOutOfRange         Code = 11
+	CodeUnimplemented      Code = 12
+	CodeInternal           Code = 13
+	CodeUnavailable        Code = 14
+	CodeDataLoss           Code = 15
+	CodeUnauthenticated    Code = 16
+)
+
+func (c Code) String() string {
+	switch c {
+	case CodeOK:
+		return "OK"
+	case CodeCanceled:
+		return "Canceled"
+	case CodeUnknown:
+		return "Unknown"
+	case CodeInvalidArgument:
+		return "InvalidArgument"
+	case CodeDeadlineExceeded:
+		return "DeadlineExceeded"
+	case CodeNotFound:
+		return "NotFound"
+	case CodeAlreadyExists:
+		return "AlreadyExists"
+	case CodePermissionDenied:
+		return "PermissionDenied"
+	case CodeResourceExhausted:
+		return "ResourceExhausted"
+	case CodeFailedPrecondition:
+		return "FailedPrecondition"
+	case CodeAborted:
+		return "Aborted"
+	case CodeOutOfRange:
+		return "OutOfRange"
+	case CodeUnimplemented:
+		return "Unimplemented"
+	case CodeInternal:
+		return "Internal"
+	case CodeUnavailable:
+		return "Unavailable"
+	case CodeDataLoss:
+		return "DataLoss"
+	case CodeUnauthenticated:
+		return "Unauthenticated"
+	}
+
+	return fmt.Sprintf("Code(%d)", uint32(c))
+}
+
+// Error is the error of the call, which crosses the wire keeping its
+// structure. Handlers can return it, so clients can get it with [errors.As]
+// or [CodeOf] and branch on the code.
+//
+// Details can hold any JSON-serializable values. To receive them typed on
+// the client side, register their types with [RegisterErrorDetail],
+// otherwise they are received as [json.RawMessage].
+type Error struct {
+	Code    Code
+	Message string
+	Details []any
+}
+
+func NewError(code Code, msg string, details ...any) *Error {
+	return &Error{Code: code, Message: msg, Details: details}
+}
+
+func Errorf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// CodeOf returns the code of the [Error] in err's chain. It returns
+// [CodeOK] for nil error and [CodeUnknown] if there is no [Error] in the
+// chain.
+func CodeOf(err error) Code {
+	if err == nil {
+		return CodeOK
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return CodeUnknown
+}
+
+// ErrorDetail returns the first detail of type T of the [Error] in err's
+// chain.
+func ErrorDetail[T any](err error) (T, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		for _, d := range e.Details {
+			if v, ok := d.(T); ok {
+				return v, true
+			}
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// toError returns the [Error] from err's chain or wraps err into an [Error]
+// with code.
+func toError(err error, code Code) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	return &Error{Code: code, Message: err.Error()}
+}
+
+var errorDetails sync.Map // type name -> reflect.Type
+
+// RegisterErrorDetail registers the type of the [Error] details, so they
+// are decoded as T instead of [json.RawMessage].
+func RegisterErrorDetail[T any]() {
+	t := reflect.TypeFor[T]()
+	errorDetails.Store(typeName(t), t)
+}
+
+func typeName(t reflect.Type) string {
+	if t.Name() != "" && t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+
+	return t.String()
+}
+
+type wireError struct {
+	Code    Code         `json:"code"`
+	Message string       `json:"message"`
+	Details []wireDetail `json:"details,omitempty"`
+}
+
+type wireDetail struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalError encodes err for transports, which can't pass it as is. Errors
+// other than [Error] are encoded as [CodeUnknown]. Details, that can't be
+// encoded to JSON, are dropped.
+func MarshalError(err error) []byte {
+	e := toError(err, CodeUnknown)
+
+	we := wireError{Code: e.Code, Message: e.Message}
+	for _, d := range e.Details {
+		val, err := json.Marshal(d)
+		if err != nil {
+			continue
+		}
+		we.Details = append(we.Details, wireDetail{
+			Type:  typeName(reflect.TypeOf(d)),
+			Value: val,
+		})
+	}
+
+	b, err := json.Marshal(we)
+	assert(err == nil)
+
+	return b
+}
+
+// UnmarshalError decodes the error, encoded with [MarshalError]. If data
+// is not an encoded error, it is used as a message of [CodeUnknown] error.
+func UnmarshalError(data []byte) *Error {
+	var we wireError
+	if err := json.Unmarshal(data, &we); err != nil {
+		return &Error{Code: CodeUnknown, Message: string(data)}
+	}
+
+	e := &Error{Code: we.Code, Message: we.Message}
+	for _, wd := range we.Details {
+		e.Details = append(e.Details, decodeDetail(wd))
+	}
+
+	return e
+}
+
+func decodeDetail(wd wireDetail) any {
+	t, ok := errorDetails.Load(wd.Type)
+	if !ok {
+		return wd.Value
+	}
+
+	v := reflect.New(t.(reflect.Type))
+	if err := json.Unmarshal(wd.Value, v.Interface()); err != nil {
+		return wd.Value
+	}
+
+	return v.Elem().Interface()
+}
@@ -0,0 +1,48 @@
+package srpc_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+)
+
+type quotaDetail struct {
+	Limit int `json:"limit"`
+}
+
+type unregisteredDetail struct {
+	Field string `json:"field"`
+}
+
+func TestErrorMarshal(t *testing.T) {
+	srpc.RegisterErrorDetail[quotaDetail]()
+
+	err := srpc.NewError(srpc.CodeResourceExhausted, "quota exceeded", quotaDetail{Limit: 10}, unregisteredDetail{Field: "x"})
+	got := srpc.UnmarshalError(srpc.MarshalError(fmt.Errorf("wrapped: %w", err)))
+
+	require.Equal(t, srpc.CodeResourceExhausted, got.Code)
+	require.Equal(t, "quota exceeded", got.Message)
+	require.Equal(t, []any{quotaDetail{Limit: 10}, json.RawMessage(`{"field":"x"}`)}, got.Details)
+
+	detail, ok := srpc.ErrorDetail[quotaDetail](got)
+	require.True(t, ok)
+	require.Equal(t, 10, detail.Limit)
+
+	// plain errors become unknown
+	got = srpc.UnmarshalError(srpc.MarshalError(errors.New("boom")))
+	require.Equal(t, &srpc.Error{Code: srpc.CodeUnknown, Message: "boom"}, got)
+
+	// not an encoded error
+	got = srpc.UnmarshalError([]byte("plain text"))
+	require.Equal(t, &srpc.Error{Code: srpc.CodeUnknown, Message: "plain text"}, got)
+}
+
+func TestCodeOf(t *testing.T) {
+	require.Equal(t, srpc.CodeOK, srpc.CodeOf(nil))
+	require.Equal(t, srpc.CodeUnknown, srpc.CodeOf(errors.New("boom")))
+	require.Equal(t, srpc.CodeNotFound, srpc.CodeOf(fmt.Errorf("%w: %w", srpc.ErrServiceError, srpc.Errorf(srpc.CodeNotFound, "user %d", 1))))
+}
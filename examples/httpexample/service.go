@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 
-	"github.com/tymbaca/srpc/cmd/tymbaca/inner"
+	"github.com/tymbaca/srpc/examples/httpexample/inner"
 )
 
 // NOTE: srpc-gen - is a code generation tool that creates type-safe client and
@@ -5,7 +5,7 @@ package main
 import (
 	"context"
 	"github.com/tymbaca/srpc"
-	"github.com/tymbaca/srpc/cmd/tymbaca/inner"
+	"github.com/tymbaca/srpc/examples/httpexample/inner"
 )
 
 func NewTestServiceClient(client *srpc.Client) *TestServiceClient {
@@ -16,17 +16,17 @@ type TestServiceClient struct {
 	client *srpc.Client
 }
 
-func (c *TestServiceClient) Add(ctx context.Context, req AddReq) (resp AddResp, err error) {
-	err = c.client.Call(ctx, "TestService.Add", req, &resp)
+func (c *TestServiceClient) Add(ctx context.Context, req AddReq, opts ...srpc.CallOption) (resp AddResp, err error) {
+	err = c.client.Call(ctx, "TestService.Add", req, &resp, opts...)
 	return resp, err
 }
 
-func (c *TestServiceClient) Divide(ctx context.Context, req DivideReq) (resp DivideResp, err error) {
-	err = c.client.Call(ctx, "TestService.Divide", req, &resp)
+func (c *TestServiceClient) Divide(ctx context.Context, req DivideReq, opts ...srpc.CallOption) (resp DivideResp, err error) {
+	err = c.client.Call(ctx, "TestService.Divide", req, &resp, opts...)
 	return resp, err
 }
 
-func (c *TestServiceClient) Multiply(ctx context.Context, req inner.MultiplyReq) (resp inner.MultiplyResp, err error) {
-	err = c.client.Call(ctx, "TestService.Multiply", req, &resp)
+func (c *TestServiceClient) Multiply(ctx context.Context, req inner.MultiplyReq, opts ...srpc.CallOption) (resp inner.MultiplyResp, err error) {
+	err = c.client.Call(ctx, "TestService.Multiply", req, &resp, opts...)
 	return resp, err
 }
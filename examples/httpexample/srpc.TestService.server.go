@@ -6,7 +6,7 @@ import (
 	"context"
 
 	"github.com/tymbaca/srpc"
-	"github.com/tymbaca/srpc/cmd/tymbaca/inner"
+	"github.com/tymbaca/srpc/examples/httpexample/inner"
 )
 
 // NOTE: this server file need to be edited after generation. Here you can fill
@@ -0,0 +1,45 @@
+package health
+
+import (
+	"context"
+	"iter"
+
+	"github.com/tymbaca/srpc"
+)
+
+func NewHealthClient(client *srpc.Client) *HealthClient {
+	return &HealthClient{Client: client}
+}
+
+// HealthClient is the client of [Health] service.
+type HealthClient struct {
+	*srpc.Client
+}
+
+func (c *HealthClient) Check(ctx context.Context, req CheckReq, opts ...srpc.CallOption) (resp CheckResp, err error) {
+	err = c.Client.Call(ctx, "Health.Check", req, &resp, opts...)
+	return resp, err
+}
+
+func (c *HealthClient) Watch(ctx context.Context, req CheckReq, opts ...srpc.CallOption) iter.Seq2[CheckResp, error] {
+	return srpc.CallServerStream[CheckResp](ctx, c.Client, "Health.Watch", req, opts...)
+}
+
+// WaitServing waits until the service is [Serving]. It returns the error if
+// the watch fails or ctx is done first.
+func (c *HealthClient) WaitServing(ctx context.Context, service string) error {
+	for resp, err := range c.Watch(ctx, CheckReq{Service: service}) {
+		if err != nil {
+			return err
+		}
+		if resp.Status == Serving {
+			return nil
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return srpc.Errorf(srpc.CodeUnavailable, "watch of %q is over before the service is serving", service)
+}
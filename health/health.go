@@ -0,0 +1,197 @@
+// Package health provides the standard health checking service, so load
+// balancers and orchestrators can probe srpc servers:
+//
+//	h := health.NewHealth(server)
+//	srpc.Register(server, h)
+//	h.SetServingStatus("Users", health.NotServing)
+//
+// The status of the server as a whole is kept for the empty service name.
+// Create the service after the other services are registered, so they are
+// reported as [Serving]. Once the server starts shutting down, all services
+// are reported as [NotServing].
+package health
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tymbaca/srpc"
+)
+
+type ServingStatus int
+
+const (
+	Unknown        ServingStatus = iota
+	Serving                      // service can handle calls
+	NotServing                   // service can't handle calls, e.g. the server is shutting down
+	ServiceUnknown               // service is not known, only sent by Watch
+)
+
+func (s ServingStatus) String() string {
+	switch s {
+	case Unknown:
+		return "UNKNOWN"
+	case Serving:
+		return "SERVING"
+	case NotServing:
+		return "NOT_SERVING"
+	case ServiceUnknown:
+		return "SERVICE_UNKNOWN"
+	}
+
+	return ""
+}
+
+type (
+	CheckReq struct {
+		Service string // empty for the server as a whole
+	}
+	CheckResp struct {
+		Status ServingStatus
+	}
+)
+
+// Health is the health checking service. It is safe for concurrent use.
+type Health struct {
+	mu       sync.Mutex
+	statuses map[string]ServingStatus
+	watchers map[string]map[chan ServingStatus]struct{}
+	shutdown bool
+}
+
+// NewHealth returns the service, which reports the server as a whole and
+// its registered services as [Serving], until the server starts shutting
+// down.
+func NewHealth(server *srpc.Server) *Health {
+	h := &Health{
+		statuses: map[string]ServingStatus{"": Serving},
+		watchers: make(map[string]map[chan ServingStatus]struct{}),
+	}
+	for _, svc := range server.Services() {
+		h.statuses[svc.Name] = Serving
+	}
+	server.RegisterOnShutdown(h.shutdownAll)
+
+	return h
+}
+
+// SetServingStatus sets the status of the service and notifies its
+// watchers. It does nothing once the server starts shutting down.
+func (h *Health) SetServingStatus(service string, status ServingStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.shutdown {
+		return
+	}
+	h.setStatus(service, status)
+}
+
+// shutdownAll sets all services to [NotServing].
+func (h *Health) shutdownAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.shutdown = true
+	for service := range h.statuses {
+		h.setStatus(service, NotServing)
+	}
+}
+
+// setStatus sets the status of the service and notifies its watchers. h.mu
+// must be held.
+func (h *Health) setStatus(service string, status ServingStatus) {
+	h.statuses[service] = status
+	for ch := range h.watchers[service] {
+		notify(ch, status)
+	}
+}
+
+// Check returns the status of the service. It fails with
+// [srpc.CodeNotFound] if the status of the service is not set.
+func (h *Health) Check(ctx context.Context, req CheckReq) (CheckResp, error) {
+	if isShuttingDown(ctx) {
+		return CheckResp{Status: NotServing}, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status, ok := h.statuses[req.Service]
+	if !ok {
+		return CheckResp{}, srpc.Errorf(srpc.CodeNotFound, "unknown service %q", req.Service)
+	}
+
+	return CheckResp{Status: status}, nil
+}
+
+// Watch sends the status of the service and then every change of it. Unlike
+// Check, it reports unknown services with [ServiceUnknown]. Once the server
+// starts shutting down, Watch sends [NotServing] and returns.
+func (h *Health) Watch(ctx context.Context, req CheckReq, stream *srpc.ServerStream[CheckResp]) error {
+	ch, status := h.watch(req.Service)
+	defer h.unwatch(req.Service, ch)
+
+	for {
+		if err := stream.Send(CheckResp{Status: status}); err != nil {
+			return err
+		}
+
+		select {
+		case status = <-ch:
+		case <-srpc.ShutdownSignal(ctx):
+			return stream.Send(CheckResp{Status: NotServing})
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// watch subscribes to the changes of the service status, returning the
+// current one.
+func (h *Health) watch(service string) (chan ServingStatus, ServingStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan ServingStatus, 1)
+	if h.watchers[service] == nil {
+		h.watchers[service] = make(map[chan ServingStatus]struct{})
+	}
+	h.watchers[service][ch] = struct{}{}
+
+	status, ok := h.statuses[service]
+	if !ok {
+		status = ServiceUnknown
+	}
+
+	return ch, status
+}
+
+func (h *Health) unwatch(service string, ch chan ServingStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.watchers[service], ch)
+	if len(h.watchers[service]) == 0 {
+		delete(h.watchers, service)
+	}
+}
+
+// notify replaces the pending status of the watcher, so slow watchers get
+// the latest one.
+func notify(ch chan ServingStatus, status ServingStatus) {
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- status
+}
+
+func isShuttingDown(ctx context.Context) bool {
+	select {
+	case <-srpc.ShutdownSignal(ctx):
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/testdata"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+// next receives the next status from the watch.
+func next(t *testing.T, statuses <-chan ServingStatus) ServingStatus {
+	t.Helper()
+
+	select {
+	case status := <-statuses:
+		return status
+	case <-time.After(time.Second):
+		t.Fatal("no status received")
+		return Unknown
+	}
+}
+
+func watch(ctx context.Context, t *testing.T, c *HealthClient, service string) (<-chan ServingStatus, <-chan error) {
+	statuses := make(chan ServingStatus)
+	done := make(chan error, 1)
+	go func() {
+		for resp, err := range c.Watch(ctx, CheckReq{Service: service}) {
+			if err != nil {
+				done <- err
+				return
+			}
+			statuses <- resp.Status
+		}
+		done <- nil
+	}()
+
+	return statuses, done
+}
+
+// startHealth starts the server of TestService and the health service.
+func startHealth(t *testing.T) (*Health, *srpctest.Server) {
+	server := testdata.NewTestServiceServer(srpc.NewServer(codec.JSON)).Server
+	h := NewHealth(server)
+	srpc.Register(server, h)
+
+	return h, srpctest.Serve(t, nil, server)
+}
+
+func TestHealth(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("check", func(t *testing.T) {
+		h, server := startHealth(t)
+		c := NewHealthClient(server.Client(t))
+
+		resp, err := c.Check(ctx, CheckReq{})
+		require.NoError(t, err)
+		require.Equal(t, Serving, resp.Status)
+
+		// registered services are serving
+		resp, err = c.Check(ctx, CheckReq{Service: "TestService"})
+		require.NoError(t, err)
+		require.Equal(t, Serving, resp.Status)
+
+		_, err = c.Check(ctx, CheckReq{Service: "Users"})
+		require.Equal(t, srpc.CodeNotFound, srpc.CodeOf(err))
+
+		h.SetServingStatus("Users", NotServing)
+		resp, err = c.Check(ctx, CheckReq{Service: "Users"})
+		require.NoError(t, err)
+		require.Equal(t, NotServing, resp.Status)
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		h, server := startHealth(t)
+		c := NewHealthClient(server.Client(t))
+
+		watchCtx, cancel := context.WithCancel(ctx)
+		statuses, done := watch(watchCtx, t, c, "Users")
+		require.Equal(t, ServiceUnknown, next(t, statuses))
+
+		h.SetServingStatus("Users", Serving)
+		require.Equal(t, Serving, next(t, statuses))
+		h.SetServingStatus("Users", NotServing)
+		require.Equal(t, NotServing, next(t, statuses))
+
+		cancel()
+		require.Error(t, <-done)
+	})
+
+	t.Run("wait serving", func(t *testing.T) {
+		h, server := startHealth(t)
+		c := NewHealthClient(server.Client(t))
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			h.SetServingStatus("Users", Serving)
+		}()
+		require.NoError(t, c.WaitServing(ctx, "Users"))
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		h, server := startHealth(t)
+		c := NewHealthClient(server.Client(t))
+		h.SetServingStatus("Users", Serving)
+
+		statuses, done := watch(ctx, t, c, "Users")
+		require.Equal(t, Serving, next(t, statuses))
+
+		// the watch doesn't hold the shutdown
+		shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		shutdownErr := make(chan error, 1)
+		go func() { shutdownErr <- server.Shutdown(shutdownCtx) }()
+
+		require.Equal(t, NotServing, next(t, statuses))
+		require.NoError(t, <-done)
+		require.NoError(t, <-shutdownErr)
+
+		// all services are not serving and stay so
+		require.Eventually(t, func() bool {
+			resp, err := h.Check(context.Background(), CheckReq{Service: "TestService"})
+			return err == nil && resp.Status == NotServing
+		}, time.Second, time.Millisecond)
+		h.SetServingStatus("Users", Serving)
+		for _, service := range []string{"", "TestService", "Users"} {
+			resp, err := h.Check(context.Background(), CheckReq{Service: service})
+			require.NoError(t, err)
+			require.Equal(t, NotServing, resp.Status, service)
+		}
+	})
+}
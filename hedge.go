@@ -0,0 +1,242 @@
+package srpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HedgingPolicy configures hedged calls: if the response is not received
+// within Delay, a copy of the request is sent, and the call takes whichever
+// response comes first, cancelling the others. Like retries, only calls with
+// replayable requests are hedged (see [Request.GetBody]), and the method
+// should be idempotent, as it can be handled by the server multiple times.
+//
+// Methods with hedging policy are not retried.
+type HedgingPolicy struct {
+	// MaxAttempts is the maximum number of requests sent, including the
+	// first one. Values less than 2 disable hedging.
+	MaxAttempts int
+
+	// Delay is the time to wait for the response before sending the next
+	// request.
+	Delay time.Duration
+
+	// NonFatalStatuses are the statuses of the response, which don't end
+	// the call: the next request is sent immediately and the call waits for
+	// the others. Transport errors are always non-fatal.
+	NonFatalStatuses []StatusCode
+
+	// Addrs are the addresses of the hedges, taken in turn. The first request
+	// is always sent to the address of the client (or the one picked from
+	// its [Resolver]). If Addrs is empty, hedges are sent the same way.
+	Addrs []string
+}
+
+// addr returns the address of the n-th request, starting from 0. It returns
+// false if the request goes to the address of the client.
+func (p HedgingPolicy) addr(n int) (string, bool) {
+	if n == 0 || len(p.Addrs) == 0 {
+		return "", false
+	}
+
+	return p.Addrs[(n-1)%len(p.Addrs)], true
+}
+
+// HedgeStats is the statistics of hedged calls of a single method.
+type HedgeStats struct {
+	Calls     int64 // total number of calls
+	Hedges    int64 // total number of hedges sent, not counting the first requests
+	HedgeWins int64 // total number of calls, which succeeded with the response of the hedge
+}
+
+type hedgeCounters struct {
+	calls, hedges, wins atomic.Int64
+}
+
+type hedgeMetrics struct {
+	mu      sync.Mutex
+	methods map[ServiceMethod]*hedgeCounters
+}
+
+func (m *hedgeMetrics) counters(serviceMethod ServiceMethod) *hedgeCounters {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.methods == nil {
+		m.methods = make(map[ServiceMethod]*hedgeCounters)
+	}
+
+	cs, ok := m.methods[serviceMethod]
+	if !ok {
+		cs = &hedgeCounters{}
+		m.methods[serviceMethod] = cs
+	}
+
+	return cs
+}
+
+// HedgeStats returns the statistics of hedged calls per method.
+func (c *Client) HedgeStats() map[ServiceMethod]HedgeStats {
+	c.hedgeMetrics.mu.Lock()
+	defer c.hedgeMetrics.mu.Unlock()
+
+	stats := make(map[ServiceMethod]HedgeStats, len(c.hedgeMetrics.methods))
+	for sm, cs := range c.hedgeMetrics.methods {
+		stats[sm] = HedgeStats{
+			Calls:     cs.calls.Load(),
+			Hedges:    cs.hedges.Load(),
+			HedgeWins: cs.wins.Load(),
+		}
+	}
+
+	return stats
+}
+
+type addrKey struct{}
+
+// withAddr returns a copy of ctx, which makes [Client.invoke] send the
+// request to addr instead of the address of the client.
+func withAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, addrKey{}, addr)
+}
+
+// addrFromContext returns the address set with [withAddr].
+func addrFromContext(ctx context.Context) (string, bool) {
+	addr, ok := ctx.Value(addrKey{}).(string)
+	return addr, ok
+}
+
+type hedgeResult struct {
+	n        int // number of the request, 0 for the first one
+	body     io.Reader
+	finish   func(reuse bool)
+	header   Metadata
+	trailer  Metadata
+	nonFatal bool
+	err      error
+}
+
+// hedge sends the request and its hedges according to policy, returning the
+// first response with OK or fatal status. The other requests are cancelled.
+func (c *Client) hedge(ctx context.Context, serviceMethod ServiceMethod, md Metadata, reqBody requestBody, policy HedgingPolicy, callOpts callOptions) (io.Reader, func(reuse bool), error) {
+	counters := c.hedgeMetrics.counters(serviceMethod)
+	counters.calls.Add(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	results := make(chan hedgeResult, policy.MaxAttempts)
+	cancels := make([]context.CancelFunc, 0, policy.MaxAttempts)
+	classify := RetryPolicy{RetryableStatuses: policy.NonFatalStatuses, RetryTransportErrors: true}
+
+	send := func() bool {
+		n := len(cancels)
+		if n >= policy.MaxAttempts {
+			return false
+		}
+
+		body := reqBody
+		if n > 0 {
+			next, err := reqBody.getBody()
+			if err != nil {
+				return false
+			}
+			body.body = next
+			counters.hedges.Add(1)
+		}
+
+		attemptCtx := ctx
+		if addr, ok := policy.addr(n); ok {
+			attemptCtx = withAddr(attemptCtx, addr)
+		}
+		attemptCtx, cancelAttempt := context.WithCancel(attemptCtx)
+		cancels = append(cancels, cancelAttempt)
+
+		go func() {
+			var header, trailer Metadata
+			opts := callOpts
+			opts.header, opts.trailer = &header, &trailer
+
+			body, finish, nonFatal, err := c.attempt(attemptCtx, serviceMethod, md.Copy(), body, classify, opts)
+			results <- hedgeResult{n: n, body: body, finish: finish, header: header, trailer: trailer, nonFatal: nonFatal, err: err}
+		}()
+
+		return true
+	}
+
+	send()
+	pending := 1
+
+	delay := time.NewTimer(policy.Delay)
+	defer delay.Stop()
+
+	var last hedgeResult
+	for pending > 0 {
+		select {
+		case <-delay.C:
+			if send() {
+				pending++
+				delay.Reset(policy.Delay)
+			}
+		case r := <-results:
+			pending--
+
+			if r.err != nil && r.nonFatal {
+				last = r
+				if send() {
+					pending++
+					delay.Reset(policy.Delay)
+				}
+				continue
+			}
+
+			// the call is committed to r
+			for n, cancelAttempt := range cancels {
+				if n != r.n {
+					cancelAttempt()
+				}
+			}
+			go discardHedges(results, pending)
+
+			if r.n > 0 && r.err == nil {
+				counters.wins.Add(1)
+			}
+
+			return c.commitHedge(r, cancel, callOpts)
+		}
+	}
+
+	return c.commitHedge(last, cancel, callOpts)
+}
+
+// commitHedge returns the result of the call. cancel is called once the
+// response is handled.
+func (c *Client) commitHedge(r hedgeResult, cancel context.CancelFunc, callOpts callOptions) (io.Reader, func(reuse bool), error) {
+	if callOpts.header != nil && r.header != nil {
+		*callOpts.header = r.header
+	}
+	if callOpts.trailer != nil && r.trailer != nil {
+		*callOpts.trailer = r.trailer
+	}
+
+	if r.err != nil {
+		cancel()
+		return nil, nil, r.err
+	}
+
+	return r.body, func(reuse bool) {
+		r.finish(reuse)
+		cancel()
+	}, nil
+}
+
+// discardHedges releases the n remaining results of the cancelled requests.
+func discardHedges(results <-chan hedgeResult, n int) {
+	for range n {
+		r := <-results
+		if r.finish != nil {
+			r.finish(false)
+		}
+	}
+}
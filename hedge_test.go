@@ -0,0 +1,136 @@
+package srpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/inmem"
+)
+
+// replicaService replies with its name after delay, or fails if delay is
+// negative.
+type replicaService struct {
+	name      string
+	delay     time.Duration
+	cancelled chan struct{}
+}
+
+func (s *replicaService) Get(ctx context.Context, req string) (string, error) {
+	if s.delay < 0 {
+		return "", errors.New("replica is broken")
+	}
+
+	select {
+	case <-time.After(s.delay):
+		return s.name, nil
+	case <-ctx.Done():
+		close(s.cancelled)
+		return "", ctx.Err()
+	}
+}
+
+func newReplica(name string, delay time.Duration) *replicaService {
+	return &replicaService{name: name, delay: delay, cancelled: make(chan struct{})}
+}
+
+func TestHedging(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("hedge wins", func(t *testing.T) {
+		cluster := inmem.New()
+		slow := newReplica("slow", time.Minute)
+		slowAddr := srpctest.Start(t, cluster, slow, "Replica").Addr
+		fastAddr := srpctest.Start(t, cluster, newReplica("fast", 0), "Replica").Addr
+
+		c := srpc.NewClient(slowAddr, codec.JSON, cluster.NewPeer(), srpc.WithHedgingPolicy("Replica.Get", srpc.HedgingPolicy{
+			MaxAttempts: 2,
+			Delay:       10 * time.Millisecond,
+			Addrs:       []string{fastAddr},
+		}))
+		defer c.Close()
+
+		var resp string
+		require.NoError(t, c.Call(ctx, "Replica.Get", "", &resp))
+		require.Equal(t, "fast", resp)
+		require.Equal(t, srpc.HedgeStats{Calls: 1, Hedges: 1, HedgeWins: 1}, c.HedgeStats()["Replica.Get"])
+
+		// the loser is cancelled
+		select {
+		case <-slow.cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("slow call is not cancelled")
+		}
+	})
+
+	t.Run("no hedge for fast response", func(t *testing.T) {
+		cluster := inmem.New()
+		fastAddr := srpctest.Start(t, cluster, newReplica("fast", 0), "Replica").Addr
+
+		c := srpc.NewClient(fastAddr, codec.JSON, cluster.NewPeer(), srpc.WithHedgingPolicy("Replica.Get", srpc.HedgingPolicy{
+			MaxAttempts: 3,
+			Delay:       time.Second,
+		}))
+		defer c.Close()
+
+		var resp string
+		require.NoError(t, c.Call(ctx, "Replica.Get", "", &resp))
+		require.Equal(t, "fast", resp)
+		require.Equal(t, srpc.HedgeStats{Calls: 1}, c.HedgeStats()["Replica.Get"])
+	})
+
+	t.Run("non-fatal status", func(t *testing.T) {
+		cluster := inmem.New()
+		brokenAddr := srpctest.Start(t, cluster, newReplica("broken", -1), "Replica").Addr
+		fastAddr := srpctest.Start(t, cluster, newReplica("fast", 0), "Replica").Addr
+
+		c := srpc.NewClient(brokenAddr, codec.JSON, cluster.NewPeer(), srpc.WithHedgingPolicy("Replica.Get", srpc.HedgingPolicy{
+			MaxAttempts:      2,
+			Delay:            time.Minute,
+			NonFatalStatuses: []srpc.StatusCode{srpc.StatusErrorFromService},
+			Addrs:            []string{fastAddr},
+		}))
+		defer c.Close()
+
+		var resp string
+		require.NoError(t, c.Call(ctx, "Replica.Get", "", &resp))
+		require.Equal(t, "fast", resp)
+	})
+
+	t.Run("fatal status", func(t *testing.T) {
+		cluster := inmem.New()
+		brokenAddr := srpctest.Start(t, cluster, newReplica("broken", -1), "Replica").Addr
+		fastAddr := srpctest.Start(t, cluster, newReplica("fast", 0), "Replica").Addr
+
+		c := srpc.NewClient(brokenAddr, codec.JSON, cluster.NewPeer(), srpc.WithHedgingPolicy("Replica.Get", srpc.HedgingPolicy{
+			MaxAttempts: 2,
+			Delay:       time.Minute,
+			Addrs:       []string{fastAddr},
+		}))
+		defer c.Close()
+
+		var resp string
+		require.ErrorIs(t, c.Call(ctx, "Replica.Get", "", &resp), srpc.ErrServiceError)
+		require.Equal(t, srpc.HedgeStats{Calls: 1}, c.HedgeStats()["Replica.Get"])
+	})
+
+	t.Run("all fail", func(t *testing.T) {
+		cluster := inmem.New()
+		brokenAddr := srpctest.Start(t, cluster, newReplica("broken", -1), "Replica").Addr
+
+		c := srpc.NewClient(brokenAddr, codec.JSON, cluster.NewPeer(), srpc.WithHedgingPolicy("Replica.Get", srpc.HedgingPolicy{
+			MaxAttempts:      3,
+			NonFatalStatuses: []srpc.StatusCode{srpc.StatusErrorFromService},
+		}))
+		defer c.Close()
+
+		var resp string
+		require.ErrorIs(t, c.Call(ctx, "Replica.Get", "", &resp), srpc.ErrServiceError)
+		require.Equal(t, srpc.HedgeStats{Calls: 1, Hedges: 2}, c.HedgeStats()["Replica.Get"])
+	})
+}
@@ -0,0 +1,55 @@
+package srpc
+
+import "context"
+
+// UnaryHandler handles the request on the server side. It is the final step
+// of the server interceptor chain.
+type UnaryHandler func(ctx context.Context, req Request) Response
+
+// UnaryServerInterceptor wraps every call handled by the [Server]. It can
+// inspect or modify the request, its metadata and the context before passing
+// them to next, and inspect or modify the returned response.
+//
+// To short-circuit the call interceptor can return its own response without
+// calling next, e.g.:
+//
+//	return Response{StatusCode: StatusErrorFromService, Error: Errorf(CodePermissionDenied, "unauthorized")}
+type UnaryServerInterceptor func(ctx context.Context, req Request, next UnaryHandler) Response
+
+// UnaryInvoker sends the request to the remote server. It is the final step
+// of the client interceptor chain.
+type UnaryInvoker func(ctx context.Context, req Request) (Response, error)
+
+// UnaryClientInterceptor wraps every [Client.Call]. It can inspect or modify
+// the request, its metadata and the context before passing them to invoke,
+// and inspect or modify the returned response.
+//
+// To short-circuit the call interceptor can return a response with non-OK
+// status or an error without calling invoke.
+type UnaryClientInterceptor func(ctx context.Context, req Request, invoke UnaryInvoker) (Response, error)
+
+// chainServerInterceptors builds a handler, which will call interceptors in
+// the provided order, the first one being the outermost.
+func chainServerInterceptors(interceptors []UnaryServerInterceptor, handler UnaryHandler) UnaryHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req Request) Response {
+			return interceptor(ctx, req, next)
+		}
+	}
+
+	return handler
+}
+
+// chainClientInterceptors builds an invoker, which will call interceptors in
+// the provided order, the first one being the outermost.
+func chainClientInterceptors(interceptors []UnaryClientInterceptor, invoker UnaryInvoker) UnaryInvoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, req Request) (Response, error) {
+			return interceptor(ctx, req, next)
+		}
+	}
+
+	return invoker
+}
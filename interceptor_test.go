@@ -0,0 +1,71 @@
+package srpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/inmem"
+	"github.com/tymbaca/srpc/transport/testdata"
+)
+
+func TestInterceptors(t *testing.T) {
+	ctx := t.Context()
+
+	var calls []string
+	serverInterceptor := func(name string) srpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req srpc.Request, next srpc.UnaryHandler) srpc.Response {
+			calls = append(calls, "server "+name)
+			return next(ctx, req)
+		}
+	}
+	clientInterceptor := func(name string) srpc.UnaryClientInterceptor {
+		return func(ctx context.Context, req srpc.Request, invoke srpc.UnaryInvoker) (srpc.Response, error) {
+			calls = append(calls, "client "+name)
+			return invoke(ctx, req)
+		}
+	}
+
+	server := srpctest.StartTestService(t,
+		srpc.WithUnaryServerInterceptors(serverInterceptor("1"), serverInterceptor("2")),
+		srpc.WithUnaryServerInterceptors(func(ctx context.Context, req srpc.Request, next srpc.UnaryHandler) srpc.Response {
+			if req.ServiceMethod == "TestService.Divide" {
+				return srpc.Response{StatusCode: srpc.StatusErrorFromService, Error: errors.New("forbidden")}
+			}
+			return next(ctx, req)
+		}),
+	)
+
+	client := testdata.NewTestServiceClient(server.Client(t,
+		srpc.WithUnaryClientInterceptors(clientInterceptor("1"), clientInterceptor("2")),
+	))
+
+	resp, err := client.Add(ctx, testdata.AddReq{A: 1, B: 2})
+	require.NoError(t, err)
+	require.Equal(t, 3, resp.Result)
+	require.Equal(t, []string{"client 1", "client 2", "server 1", "server 2"}, calls)
+
+	_, err = client.Divide(ctx, testdata.DivideReq{A: 4, B: 2})
+	require.ErrorIs(t, err, srpc.ErrServiceError)
+	require.ErrorContains(t, err, "forbidden")
+}
+
+func TestClientInterceptorShortCircuit(t *testing.T) {
+	ctx := t.Context()
+
+	cluster := inmem.New()
+	errDenied := errors.New("denied")
+
+	client := testdata.NewTestServiceClient(srpc.NewClient("unknown", codec.JSON, cluster.NewPeer(),
+		srpc.WithUnaryClientInterceptors(func(ctx context.Context, req srpc.Request, invoke srpc.UnaryInvoker) (srpc.Response, error) {
+			return srpc.Response{}, errDenied
+		}),
+	))
+
+	_, err := client.Add(ctx, testdata.AddReq{A: 1, B: 2})
+	require.ErrorIs(t, err, errDenied)
+}
@@ -0,0 +1,80 @@
+// Package srpctest starts the servers of the tests over the in-memory
+// transport.
+package srpctest
+
+import (
+	"testing"
+
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/codec/cbor"
+	"github.com/tymbaca/srpc/codec/msgpack"
+	"github.com/tymbaca/srpc/transport/inmem"
+	"github.com/tymbaca/srpc/transport/testdata"
+)
+
+// Codecs are the codecs, which encode the messages of [testdata] as is, for
+// the tests and benchmarks run with each of them.
+var Codecs = []struct {
+	Name  string
+	Codec srpc.Codec
+}{
+	{"json", codec.JSON},
+	{"msgpack", msgpack.Codec},
+	{"cbor", cbor.Codec},
+}
+
+// Server is the server started with [Start].
+type Server struct {
+	*srpc.Server
+
+	// Cluster is the in-memory network of the server, clients are created
+	// on its new peers.
+	Cluster *inmem.Cluster
+	// Addr is the address of the server in Cluster.
+	Addr string
+	// Done receives the result of [srpc.Server.Start] once it returns.
+	Done <-chan error
+}
+
+// Start starts the server with svc registered under name (see
+// [srpc.RegisterWithName]) on the new peer of cluster, or of the new cluster
+// if it is nil. The server uses [codec.JSON] and is closed once the test is
+// over.
+func Start[T any](t testing.TB, cluster *inmem.Cluster, svc T, name string, opts ...srpc.ServerOption) *Server {
+	server := srpc.NewServer(codec.JSON, opts...)
+	srpc.RegisterWithName(server, svc, name)
+
+	return Serve(t, cluster, server)
+}
+
+// Serve starts the server with its services already registered, see
+// [Start].
+func Serve(t testing.TB, cluster *inmem.Cluster, server *srpc.Server) *Server {
+	if cluster == nil {
+		cluster = inmem.New()
+	}
+	peer := cluster.NewPeer()
+	t.Cleanup(func() { server.Close() })
+
+	// listen before return, so the calls are accepted right away
+	l := peer.Listen()
+	done := make(chan error, 1)
+	go func() { done <- server.Start(t.Context(), l) }()
+
+	return &Server{Server: server, Cluster: cluster, Addr: peer.Addr(), Done: done}
+}
+
+// StartTestService starts the server of [testdata.TestService], see [Start].
+func StartTestService(t testing.TB, opts ...srpc.ServerOption) *Server {
+	return Start[testdata.TestService](t, nil, &testdata.TestServiceServer{}, "", opts...)
+}
+
+// Client returns the client of the server on the new peer of its cluster.
+// It is closed once the test is over.
+func (s *Server) Client(t testing.TB, opts ...srpc.ClientOption) *srpc.Client {
+	c := srpc.NewClient(s.Addr, codec.JSON, s.Cluster.NewPeer(), opts...)
+	t.Cleanup(func() { c.Close() })
+
+	return c
+}
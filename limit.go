@@ -0,0 +1,209 @@
+package srpc
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimit is the token bucket limit of the calls: Burst calls can be made
+// at once, then the calls are allowed at Rate per second.
+type RateLimit struct {
+	// Rate is the number of calls per second.
+	Rate float64
+
+	// Burst is the size of the bucket. Zero means Rate, but at least 1.
+	Burst int
+
+	// Key is the metadata key, e.g. the tenant ID. If set, the limit applies
+	// to every value of the key separately. Calls without the key share one
+	// bucket.
+	Key string
+}
+
+// limits are the concurrency and rate limits of the server. Limits are
+// keyed by the name of the service ("Service"), the method
+// ("Service.Method"), or "" for all calls.
+type limits struct {
+	mu          sync.Mutex
+	concurrency map[string]*concurrencyLimit
+	rates       map[string]*rateLimiter
+}
+
+func (l *limits) setConcurrency(name string, n int) {
+	if l.concurrency == nil {
+		l.concurrency = make(map[string]*concurrencyLimit)
+	}
+	l.concurrency[name] = &concurrencyLimit{max: n}
+}
+
+func (l *limits) setRate(name string, limit RateLimit) {
+	if l.rates == nil {
+		l.rates = make(map[string]*rateLimiter)
+	}
+	l.rates[name] = newRateLimiter(limit)
+}
+
+type concurrencyLimit struct {
+	max      int
+	inFlight int
+}
+
+// acquireServer takes the server-wide concurrency limit. It is called before
+// a goroutine is spawned for the call, so excess calls cost none. release
+// must be called once the call is finished.
+func (l *limits) acquireServer() (release func(), err error) {
+	if len(l.concurrency) == 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	taken, err := l.concurrencyLimits("")
+	if err != nil {
+		return nil, err
+	}
+
+	return l.take(taken), nil
+}
+
+// acquire takes the service and method limits of the call and the rate
+// limits, failing if any of them is exceeded. Nothing is taken unless all of
+// them allow the call. release must be called once the call is finished.
+func (l *limits) acquire(serviceMethod ServiceMethod, md Metadata) (release func(), err error) {
+	if len(l.concurrency) == 0 && len(l.rates) == 0 {
+		return func() {}, nil
+	}
+
+	service, _, _ := serviceMethod.Split()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	taken, err := l.concurrencyLimits(service, string(serviceMethod))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var buckets []*bucket
+	for _, name := range []string{"", service, string(serviceMethod)} {
+		r, ok := l.rates[name]
+		if !ok {
+			continue
+		}
+		b := r.bucket(md, now)
+		if b.tokens < 1 {
+			return nil, fmt.Errorf("rate limit of %s is exceeded", limitName(name))
+		}
+		buckets = append(buckets, b)
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+
+	return l.take(taken), nil
+}
+
+// concurrencyLimits returns the concurrency limits of names, failing if any
+// of them is exceeded. l.mu must be held.
+func (l *limits) concurrencyLimits(names ...string) ([]*concurrencyLimit, error) {
+	var limits []*concurrencyLimit
+	for _, name := range names {
+		c, ok := l.concurrency[name]
+		if !ok {
+			continue
+		}
+		if c.inFlight >= c.max {
+			return nil, fmt.Errorf("too many concurrent calls to %s", limitName(name))
+		}
+		limits = append(limits, c)
+	}
+
+	return limits, nil
+}
+
+// take takes the concurrency limits. l.mu must be held.
+func (l *limits) take(limits []*concurrencyLimit) (release func()) {
+	for _, c := range limits {
+		c.inFlight++
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			for _, c := range limits {
+				c.inFlight--
+			}
+		})
+	}
+}
+
+func limitName(name string) string {
+	return tern(name == "", "server", name)
+}
+
+// _maxBuckets is the number of keyed buckets, after which the full ones are
+// dropped.
+const _maxBuckets = 10000
+
+type rateLimiter struct {
+	limit   RateLimit
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.Burst <= 0 {
+		limit.Burst = max(1, int(limit.Rate))
+	}
+
+	return &rateLimiter{limit: limit, buckets: make(map[string]*bucket)}
+}
+
+// bucket returns the refilled bucket of the call. The token is taken by the
+// caller, once all the limits allow the call.
+func (r *rateLimiter) bucket(md Metadata, now time.Time) *bucket {
+	var key string
+	if r.limit.Key != "" {
+		if vals := md.Get(r.limit.Key); len(vals) > 0 {
+			key = vals[0]
+		}
+	}
+
+	b, ok := r.buckets[key]
+	if !ok {
+		if len(r.buckets) >= _maxBuckets {
+			r.prune(now)
+		}
+		b = &bucket{tokens: float64(r.limit.Burst), last: now}
+		r.buckets[key] = b
+	}
+
+	b.refill(r.limit, now)
+
+	return b
+}
+
+func (b *bucket) refill(limit RateLimit, now time.Time) {
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+}
+
+// prune drops the buckets, which are full, so they are the same as new ones.
+func (r *rateLimiter) prune(now time.Time) {
+	for key, b := range r.buckets {
+		b.refill(r.limit, now)
+		if b.tokens >= float64(r.limit.Burst) {
+			delete(r.buckets, key)
+		}
+	}
+}
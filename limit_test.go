@@ -0,0 +1,217 @@
+package srpc_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/internal/srpctest"
+)
+
+func TestServerLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), time.Minute)
+	defer cancel()
+
+	deadline := func(ctx context.Context, c *srpc.Client) error {
+		var left time.Duration
+		return c.Call(ctx, "Blocking.Deadline", "", &left)
+	}
+
+	// block starts the call, which is in progress until svc.release is
+	// closed.
+	block := func(t *testing.T, svc *blockingService, c *srpc.Client) chan error {
+		callErr := make(chan error, 1)
+		go func() {
+			var resp string
+			callErr <- c.Call(ctx, "Blocking.Ignore", "", &resp)
+		}()
+		<-svc.started
+		return callErr
+	}
+
+	t.Run("max concurrent calls", func(t *testing.T) {
+		svc := newBlockingService()
+		c := srpctest.Start(t, nil, svc, "Blocking", srpc.WithMaxConcurrentCalls(1)).Client(t)
+		callErr := block(t, svc, c)
+
+		err := deadline(ctx, c)
+		require.ErrorIs(t, err, srpc.ErrTransportError)
+		require.Equal(t, srpc.CodeResourceExhausted, srpc.CodeOf(err))
+
+		close(svc.release)
+		require.NoError(t, <-callErr)
+
+		// the limit is released once the server finishes the call
+		require.Eventually(t, func() bool { return deadline(ctx, c) == nil }, time.Second, time.Millisecond)
+	})
+
+	t.Run("method concurrency", func(t *testing.T) {
+		svc := newBlockingService()
+		c := srpctest.Start(t, nil, svc, "Blocking", srpc.WithConcurrencyLimit("Blocking.Ignore", 1)).Client(t)
+		callErr := block(t, svc, c)
+
+		var resp string
+		err := c.Call(ctx, "Blocking.Ignore", "", &resp)
+		require.Equal(t, srpc.CodeResourceExhausted, srpc.CodeOf(err))
+		require.NoError(t, deadline(ctx, c))
+
+		close(svc.release)
+		require.NoError(t, <-callErr)
+	})
+
+	t.Run("service concurrency", func(t *testing.T) {
+		svc := newBlockingService()
+		c := srpctest.Start(t, nil, svc, "Blocking", srpc.WithConcurrencyLimit("Blocking", 1)).Client(t)
+		callErr := block(t, svc, c)
+
+		require.Equal(t, srpc.CodeResourceExhausted, srpc.CodeOf(deadline(ctx, c)))
+
+		close(svc.release)
+		require.NoError(t, <-callErr)
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		c := srpctest.Start(t, nil, newBlockingService(), "Blocking", srpc.WithRateLimit("Blocking.Deadline", srpc.RateLimit{Rate: 0.1, Burst: 2})).Client(t)
+
+		require.NoError(t, deadline(ctx, c))
+		require.NoError(t, deadline(ctx, c))
+		require.Equal(t, srpc.CodeResourceExhausted, srpc.CodeOf(deadline(ctx, c)))
+	})
+
+	t.Run("rate limit refills", func(t *testing.T) {
+		c := srpctest.Start(t, nil, newBlockingService(), "Blocking", srpc.WithRateLimit("", srpc.RateLimit{Rate: 50, Burst: 1})).Client(t)
+
+		require.NoError(t, deadline(ctx, c))
+		require.Equal(t, srpc.CodeResourceExhausted, srpc.CodeOf(deadline(ctx, c)))
+		time.Sleep(40 * time.Millisecond)
+		require.NoError(t, deadline(ctx, c))
+	})
+
+	t.Run("rate limit per key", func(t *testing.T) {
+		c := srpctest.Start(t, nil, newBlockingService(), "Blocking", srpc.WithRateLimit("Blocking", srpc.RateLimit{Rate: 0.1, Burst: 1, Key: "tenant"})).Client(t)
+
+		tenantA := srpc.AppendToOutgoingContext(ctx, "tenant", "a")
+		tenantB := srpc.AppendToOutgoingContext(ctx, "tenant", "b")
+
+		require.NoError(t, deadline(tenantA, c))
+		require.Equal(t, srpc.CodeResourceExhausted, srpc.CodeOf(deadline(tenantA, c)))
+		require.NoError(t, deadline(tenantB, c))
+	})
+
+	t.Run("rejected call takes no tokens", func(t *testing.T) {
+		c := srpctest.Start(t, nil, newBlockingService(), "Blocking",
+			srpc.WithRateLimit("", srpc.RateLimit{Rate: 0.1, Burst: 2}),
+			srpc.WithRateLimit("Blocking", srpc.RateLimit{Rate: 0.1, Burst: 1, Key: "tenant"}),
+		).Client(t)
+
+		tenantA := srpc.AppendToOutgoingContext(ctx, "tenant", "a")
+		tenantB := srpc.AppendToOutgoingContext(ctx, "tenant", "b")
+		tenantC := srpc.AppendToOutgoingContext(ctx, "tenant", "c")
+
+		require.NoError(t, deadline(tenantA, c))
+		require.Equal(t, srpc.CodeResourceExhausted, srpc.CodeOf(deadline(tenantA, c)))
+		// the server-wide bucket is not taken by the rejected call
+		require.NoError(t, deadline(tenantB, c))
+		require.Equal(t, srpc.CodeResourceExhausted, srpc.CodeOf(deadline(tenantC, c)))
+	})
+
+	t.Run("reject does not block accept", func(t *testing.T) {
+		svc := newBlockingService()
+		server := srpc.NewServer(codec.JSON, srpc.WithMaxConcurrentCalls(1))
+		srpc.RegisterWithName(server, svc, "Blocking")
+		l := newStubListener()
+		t.Cleanup(func() { server.Close() })
+		go server.Start(ctx, l)
+
+		l.conns <- newStubConn(false)
+		<-svc.started
+
+		// the client of the rejected call doesn't read the reply
+		stuck := newStubConn(true)
+		l.conns <- stuck
+		select {
+		case l.conns <- newStubConn(false):
+		case <-time.After(time.Second):
+			t.Fatal("accept loop is blocked by the reply")
+		}
+
+		replyCtx := <-stuck.replied
+		_, ok := replyCtx.Deadline()
+		require.True(t, ok, "reply has no deadline")
+		select {
+		case <-stuck.closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("connection of the rejected call is not closed")
+		}
+
+		close(svc.release)
+	})
+}
+
+// stubListener accepts the conns sent to it.
+type stubListener struct {
+	conns     chan srpc.ServerConn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newStubListener() *stubListener {
+	return &stubListener{conns: make(chan srpc.ServerConn), done: make(chan struct{})}
+}
+
+func (l *stubListener) Accept() (srpc.ServerConn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, srpc.ErrListenerClosed
+	}
+}
+
+func (l *stubListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+// stubConn is the call of Blocking.Ignore. The stuck conn doesn't return
+// from Reply until its context is done.
+type stubConn struct {
+	stuck     bool
+	replied   chan context.Context
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newStubConn(stuck bool) *stubConn {
+	return &stubConn{stuck: stuck, replied: make(chan context.Context, 1), closed: make(chan struct{})}
+}
+
+func (c *stubConn) Request() srpc.Request {
+	return srpc.Request{
+		ServiceMethod: "Blocking.Ignore",
+		Metadata:      srpc.Metadata{},
+		Body:          strings.NewReader(`""`),
+	}
+}
+
+func (c *stubConn) Addr() string             { return "stub" }
+func (c *stubConn) Context() context.Context { return context.Background() }
+
+func (c *stubConn) Reply(ctx context.Context, resp srpc.Response) error {
+	c.replied <- ctx
+	if c.stuck {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (c *stubConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
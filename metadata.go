@@ -0,0 +1,240 @@
+package srpc
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Metadata is a set of key-values, sent along with the request or response,
+// e.g. request IDs or auth tokens. Keys are case-insensitive: the methods of
+// Metadata lowercase them. Keys with "srpc-trailer-" prefix are reserved for
+// the trailer, see [SetTrailer].
+type Metadata map[string][]string
+
+// Pairs returns Metadata formed from even amount of args:
+//
+//	srpc.Pairs("key1", "val1", "key2", "val2")
+//
+// Pairs panics if odd amount of args is provided.
+func Pairs(kv ...string) Metadata {
+	if len(kv)%2 == 1 {
+		panic(fmt.Sprintf("srpc: Pairs got odd number of args: %d", len(kv)))
+	}
+
+	md := make(Metadata, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		md.Append(kv[i], kv[i+1])
+	}
+
+	return md
+}
+
+// Get returns values for the key.
+func (md Metadata) Get(key string) []string {
+	return md[strings.ToLower(key)]
+}
+
+// Set replaces values for the key.
+func (md Metadata) Set(key string, vals ...string) {
+	md[strings.ToLower(key)] = vals
+}
+
+// Append adds values to the key.
+func (md Metadata) Append(key string, vals ...string) {
+	key = strings.ToLower(key)
+	md[key] = append(md[key], vals...)
+}
+
+// Copy returns a deep copy of md.
+func (md Metadata) Copy() Metadata {
+	out := make(Metadata, len(md))
+	for k, vs := range md {
+		out[k] = slices.Clone(vs)
+	}
+
+	return out
+}
+
+// merge appends all values from other to md.
+func (md Metadata) merge(other Metadata) {
+	for k, vs := range other {
+		md.Append(k, vs...)
+	}
+}
+
+type (
+	outgoingMetadataKey struct{}
+	incomingMetadataKey struct{}
+	headerMetadataKey   struct{}
+	trailerMetadataKey  struct{}
+)
+
+// NewOutgoingContext returns a copy of ctx with md attached. [Client.Call]
+// sends it along with the request. It replaces any metadata previously
+// attached with NewOutgoingContext or [AppendToOutgoingContext].
+func NewOutgoingContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, outgoingMetadataKey{}, md.Copy())
+}
+
+// AppendToOutgoingContext returns a copy of ctx with key-values from kv
+// added to the outgoing metadata. See [Pairs] for kv format.
+func AppendToOutgoingContext(ctx context.Context, kv ...string) context.Context {
+	md, _ := FromOutgoingContext(ctx)
+	if md == nil {
+		md = Metadata{}
+	}
+	md.merge(Pairs(kv...))
+
+	return context.WithValue(ctx, outgoingMetadataKey{}, md)
+}
+
+// FromOutgoingContext returns a copy of the outgoing metadata in ctx, if any.
+func FromOutgoingContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(outgoingMetadataKey{}).(Metadata)
+	if !ok {
+		return nil, false
+	}
+
+	return md.Copy(), true
+}
+
+// NewIncomingContext returns a copy of ctx with md attached as incoming
+// metadata. [Server] does it for every request, so this is mainly useful
+// for testing handlers.
+func NewIncomingContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, incomingMetadataKey{}, md)
+}
+
+// FromIncomingContext returns the metadata of the request, which is being
+// handled with ctx.
+func FromIncomingContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(incomingMetadataKey{}).(Metadata)
+	return md, ok
+}
+
+// responseMetadata collects metadata set by handler and server interceptors.
+type responseMetadata struct {
+	mu sync.Mutex
+	md Metadata
+}
+
+func withResponseHeader(ctx context.Context) (context.Context, *responseMetadata) {
+	h := &responseMetadata{md: Metadata{}}
+	return context.WithValue(ctx, headerMetadataKey{}, h), h
+}
+
+func withResponseTrailer(ctx context.Context) (context.Context, *responseMetadata) {
+	t := &responseMetadata{md: Metadata{}}
+	return context.WithValue(ctx, trailerMetadataKey{}, t), t
+}
+
+// SetHeader adds md to the metadata of the response for the request being
+// handled with ctx. It can be called multiple times, all values are merged.
+// SetHeader returns an error if ctx doesn't belong to a handled request or
+// md has the keys reserved for the trailer.
+func SetHeader(ctx context.Context, md Metadata) error {
+	h, ok := ctx.Value(headerMetadataKey{}).(*responseMetadata)
+	if !ok {
+		return fmt.Errorf("srpc: SetHeader: context doesn't belong to a handled request")
+	}
+	if err := checkReservedKeys(md); err != nil {
+		return fmt.Errorf("srpc: SetHeader: %w", err)
+	}
+
+	h.merge(md)
+	return nil
+}
+
+// SetTrailer adds md to the trailer of the response for the request being
+// handled with ctx. Unlike the header, the trailer is sent once the method
+// returns, so streaming methods can set it after the messages are sent,
+// e.g. to report the stats of the stream. It can be called multiple times,
+// all values are merged. SetTrailer returns an error if ctx doesn't belong
+// to a handled request.
+func SetTrailer(ctx context.Context, md Metadata) error {
+	t, ok := ctx.Value(trailerMetadataKey{}).(*responseMetadata)
+	if !ok {
+		return fmt.Errorf("srpc: SetTrailer: context doesn't belong to a handled request")
+	}
+
+	t.merge(md)
+	return nil
+}
+
+// responseTrailer returns the trailer set for the request being handled with
+// ctx.
+func responseTrailer(ctx context.Context) Metadata {
+	t, ok := ctx.Value(trailerMetadataKey{}).(*responseMetadata)
+	if !ok {
+		return nil
+	}
+
+	return t.metadata()
+}
+
+func (rm *responseMetadata) merge(md Metadata) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.md.merge(md)
+}
+
+func (rm *responseMetadata) metadata() Metadata {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	return maps.Clone(rm.md)
+}
+
+// _trailerPrefix prefixes the keys of the trailer, which is sent in the
+// metadata of the complete (i.e. not streamed) response along with the
+// header.
+const _trailerPrefix = "srpc-trailer-"
+
+// checkReservedKeys returns an error if md has the keys with
+// [_trailerPrefix], as they would be taken for the trailer.
+func checkReservedKeys(md Metadata) error {
+	for k := range md {
+		if strings.HasPrefix(strings.ToLower(k), _trailerPrefix) {
+			return fmt.Errorf("metadata key %q is reserved", k)
+		}
+	}
+
+	return nil
+}
+
+// addTrailer adds trailer to the metadata of the response.
+func addTrailer(md, trailer Metadata) {
+	for k, vs := range trailer {
+		md.Append(_trailerPrefix+k, vs...)
+	}
+}
+
+// splitTrailer splits the metadata of the response into the header and the
+// trailer, see [addTrailer].
+func splitTrailer(md Metadata) (header, trailer Metadata) {
+	for k := range md {
+		if strings.HasPrefix(k, _trailerPrefix) {
+			trailer = Metadata{}
+			break
+		}
+	}
+	if trailer == nil {
+		return md, nil
+	}
+
+	header = make(Metadata, len(md))
+	for k, vs := range md {
+		if name, ok := strings.CutPrefix(k, _trailerPrefix); ok {
+			trailer[name] = vs
+		} else {
+			header[k] = vs
+		}
+	}
+
+	return header, trailer
+}
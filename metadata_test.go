@@ -0,0 +1,126 @@
+package srpc_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/testdata"
+)
+
+func TestMetadata(t *testing.T) {
+	ctx := t.Context()
+
+	// echoes request id back in the response metadata
+	echo := func(ctx context.Context, req srpc.Request, next srpc.UnaryHandler) srpc.Response {
+		md, ok := srpc.FromIncomingContext(ctx)
+		require.True(t, ok)
+		require.NoError(t, srpc.SetHeader(ctx, srpc.Pairs("x-echo", md.Get("X-Request-ID")[0])))
+		return next(ctx, req)
+	}
+
+	server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(echo))
+	client := testdata.NewTestServiceClient(server.Client(t))
+
+	ctx = srpc.AppendToOutgoingContext(ctx, "x-request-id", "42")
+	var header srpc.Metadata
+	resp, err := client.Add(ctx, testdata.AddReq{A: 1, B: 2}, srpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, 3, resp.Result)
+	require.Equal(t, []string{"42"}, header.Get("x-echo"))
+}
+
+func TestTrailer(t *testing.T) {
+	ctx := t.Context()
+
+	setTrailer := func(ctx context.Context, req srpc.Request, next srpc.UnaryHandler) srpc.Response {
+		require.NoError(t, srpc.SetHeader(ctx, srpc.Pairs("x-header", "h")))
+		// the keys of the trailer can't be forged with the header
+		require.Error(t, srpc.SetHeader(ctx, srpc.Pairs("Srpc-Trailer-X-Method", "forged")))
+		require.NoError(t, srpc.SetTrailer(ctx, srpc.Pairs("x-method", string(req.ServiceMethod))))
+		return next(ctx, req)
+	}
+
+	server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(setTrailer))
+	client := testdata.NewTestServiceClient(server.Client(t))
+
+	t.Run("unary", func(t *testing.T) {
+		var header, trailer srpc.Metadata
+		_, err := client.Add(ctx, testdata.AddReq{A: 1, B: 2}, srpc.Header(&header), srpc.Trailer(&trailer))
+		require.NoError(t, err)
+		require.Equal(t, srpc.Pairs("x-method", "TestService.Add"), trailer)
+		require.Equal(t, []string{"h"}, header.Get("x-header"))
+		require.Empty(t, header.Get("x-method"))
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		var trailer srpc.Metadata
+		_, err := client.Divide(ctx, testdata.DivideReq{A: 1, B: 0}, srpc.Trailer(&trailer))
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.Pairs("x-method", "TestService.Divide"), trailer)
+	})
+
+	t.Run("server stream", func(t *testing.T) {
+		var header, trailer srpc.Metadata
+		var got []int
+		for resp, err := range client.Count(ctx, testdata.CountReq{N: 3}, srpc.Header(&header), srpc.Trailer(&trailer)) {
+			require.NoError(t, err)
+			got = append(got, resp.I)
+		}
+		require.Equal(t, []int{0, 1, 2}, got)
+		require.Equal(t, srpc.Pairs("x-method", "TestService.Count"), trailer)
+		require.Empty(t, header.Get("x-method"))
+	})
+
+	t.Run("client stream", func(t *testing.T) {
+		var trailer srpc.Metadata
+		call := client.Sum(ctx, srpc.Trailer(&trailer))
+		require.NoError(t, call.Send(testdata.SumReq{N: 2}))
+		resp, err := call.CloseAndRecv()
+		require.NoError(t, err)
+		require.Equal(t, 2, resp.Sum)
+		require.Equal(t, srpc.Pairs("x-method", "TestService.Sum"), trailer)
+	})
+
+	t.Run("bidi stream", func(t *testing.T) {
+		var trailer srpc.Metadata
+		call := client.Echo(ctx, srpc.Trailer(&trailer))
+		require.NoError(t, call.Send(testdata.EchoMsg{Text: "a"}))
+		msg, err := call.Recv()
+		require.NoError(t, err)
+		require.Equal(t, "a", msg.Text)
+		require.Empty(t, trailer, "trailer is sent at the end of the stream")
+
+		require.NoError(t, call.CloseSend())
+		_, err = call.Recv()
+		require.ErrorIs(t, err, io.EOF)
+		require.Equal(t, srpc.Pairs("x-method", "TestService.Echo"), trailer)
+	})
+
+	t.Run("reserved key", func(t *testing.T) {
+		ctx := srpc.AppendToOutgoingContext(ctx, "srpc-trailer-x-method", "forged")
+		_, err := client.Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.ErrorContains(t, err, `metadata key "srpc-trailer-x-method" is reserved`)
+	})
+
+	require.Error(t, srpc.SetTrailer(ctx, srpc.Pairs("a", "1")))
+}
+
+func TestOutgoingContext(t *testing.T) {
+	ctx := srpc.NewOutgoingContext(t.Context(), srpc.Pairs("a", "1"))
+	ctx = srpc.AppendToOutgoingContext(ctx, "a", "2", "B", "3")
+
+	md, ok := srpc.FromOutgoingContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, srpc.Metadata{"a": {"1", "2"}, "b": {"3"}}, md)
+
+	// returned metadata is a copy
+	md.Set("a", "changed")
+	md, _ = srpc.FromOutgoingContext(ctx)
+	require.Equal(t, []string{"1", "2"}, md.Get("a"))
+
+	require.Panics(t, func() { srpc.AppendToOutgoingContext(ctx, "odd") })
+}
@@ -4,7 +4,10 @@ import (
 	"io"
 )
 
-func ToReader(fn func(w io.Writer) error) io.Reader {
+// ToReader runs fn in a separate goroutine and returns the reader of
+// everything fn writes. Closing the reader unblocks fn if the data is not
+// needed anymore.
+func ToReader(fn func(w io.Writer) error) io.ReadCloser {
 	r, w := io.Pipe()
 
 	go func() {
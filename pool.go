@@ -0,0 +1,381 @@
+package srpc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrClientClosed = errors.New("client is closed")
+
+// PoolConfig configures the pool of connections of the [Client]. Limits are
+// applied per address.
+type PoolConfig struct {
+	// MaxIdle is the maximum number of idle connections kept for reuse.
+	// Zero means [DefaultMaxIdle], negative value disables reuse.
+	MaxIdle int
+
+	// MaxOpen is the maximum number of open connections, including ones in
+	// use. Calls wait for a free connection once it is reached. Zero means
+	// no limit.
+	MaxOpen int
+
+	// IdleTimeout is the maximum amount of time a connection may be idle
+	// before it is closed. Zero means no limit.
+	IdleTimeout time.Duration
+}
+
+const DefaultMaxIdle = 2
+
+// PoolStats is the statistics of connections to a single address.
+type PoolStats struct {
+	MaxOpenConnections int // maximum number of open connections, 0 if unlimited
+
+	OpenConnections int // number of established connections, both in use and idle
+	InUse           int // number of connections currently in use
+	Idle            int // number of idle connections
+
+	WaitCount         int64         // total number of calls waited for a connection
+	WaitDuration      time.Duration // total time blocked waiting for a new connection
+	MaxIdleClosed     int64         // total number of connections closed due to MaxIdle
+	IdleTimeoutClosed int64         // total number of connections closed due to IdleTimeout
+	UnhealthyClosed   int64         // total number of connections closed as unhealthy
+}
+
+// pool keeps connections, so they can be reused by subsequent calls.
+// Only [ReusableConn] connections are reused, others are closed after the
+// call.
+type pool struct {
+	connector Connector
+	cfg       PoolConfig
+
+	mu     sync.Mutex
+	addrs  map[string]*addrPool
+	closed bool
+}
+
+type addrPool struct {
+	idle    []*idleConn
+	waiters []chan struct{}
+	stats   PoolStats
+	retired bool // address is not resolved anymore, connections are not kept
+}
+
+type idleConn struct {
+	conn  ClientConn
+	timer *time.Timer
+}
+
+func newPool(connector Connector, cfg PoolConfig) *pool {
+	if cfg.MaxIdle == 0 {
+		cfg.MaxIdle = DefaultMaxIdle
+	}
+
+	return &pool{
+		connector: connector,
+		cfg:       cfg,
+		addrs:     make(map[string]*addrPool),
+	}
+}
+
+func (p *pool) addrPool(addr string) *addrPool {
+	ap, ok := p.addrs[addr]
+	if !ok {
+		ap = &addrPool{}
+		ap.stats.MaxOpenConnections = p.cfg.MaxOpen
+		p.addrs[addr] = ap
+	}
+
+	return ap
+}
+
+// get returns an idle connection to addr or establishes a new one.
+func (p *pool) get(ctx context.Context, addr string) (ClientConn, error) {
+	p.mu.Lock()
+
+	var waitStart time.Time
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrClientClosed
+		}
+
+		ap := p.addrPool(addr)
+		if !waitStart.IsZero() {
+			ap.stats.WaitDuration += time.Since(waitStart)
+			waitStart = time.Time{}
+		}
+
+		var unhealthy []ClientConn
+		for len(ap.idle) > 0 {
+			ic := ap.idle[len(ap.idle)-1]
+			ap.idle = ap.idle[:len(ap.idle)-1]
+			ap.stats.Idle--
+			if ic.timer != nil {
+				ic.timer.Stop()
+			}
+
+			if !isHealthy(ic.conn) {
+				ap.stats.UnhealthyClosed++
+				ap.stats.OpenConnections--
+				unhealthy = append(unhealthy, ic.conn)
+				continue
+			}
+
+			ap.stats.InUse++
+			p.mu.Unlock()
+			closeAll(unhealthy)
+			return ic.conn, nil
+		}
+
+		if p.cfg.MaxOpen <= 0 || ap.stats.OpenConnections < p.cfg.MaxOpen {
+			ap.stats.OpenConnections++
+			ap.stats.InUse++
+			p.mu.Unlock()
+			closeAll(unhealthy)
+
+			conn, err := p.connector.Connect(ctx, addr)
+			if err != nil {
+				p.mu.Lock()
+				ap.stats.OpenConnections--
+				ap.stats.InUse--
+				ap.wakeWaiter()
+				p.mu.Unlock()
+				return nil, err
+			}
+
+			return conn, nil
+		}
+
+		// wait for a connection to be returned or closed
+		ap.stats.WaitCount++
+		waitStart = time.Now()
+		wait := make(chan struct{}, 1)
+		ap.waiters = append(ap.waiters, wait)
+		p.mu.Unlock()
+		closeAll(unhealthy)
+
+		select {
+		case <-wait:
+			p.mu.Lock()
+		case <-ctx.Done():
+			p.mu.Lock()
+			ap.stats.WaitDuration += time.Since(waitStart)
+			ap.removeWaiter(wait)
+			select {
+			case <-wait:
+				ap.wakeWaiter() // pass the wakeup to someone else
+			default:
+			}
+			p.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// put returns conn, taken with get, back to the pool. If reuse is false or
+// conn can't be reused, it is closed.
+func (p *pool) put(addr string, conn ClientConn, reuse bool) {
+	p.mu.Lock()
+	ap := p.addrPool(addr)
+	ap.stats.InUse--
+
+	_, reusable := conn.(ReusableConn)
+	switch {
+	case !reuse || !reusable || p.closed || p.cfg.MaxIdle < 0 || ap.retired:
+	case !isHealthy(conn):
+		ap.stats.UnhealthyClosed++
+	case len(ap.idle) >= p.cfg.MaxIdle && len(ap.waiters) == 0:
+		// idle conns above the limit are kept only for the waiters, woken
+		// by put
+		ap.stats.MaxIdleClosed++
+	default:
+		ic := &idleConn{conn: conn}
+		if p.cfg.IdleTimeout > 0 {
+			ic.timer = time.AfterFunc(p.cfg.IdleTimeout, func() { p.expire(addr, ic) })
+		}
+		ap.idle = append(ap.idle, ic)
+		ap.stats.Idle++
+		ap.wakeWaiter()
+		p.mu.Unlock()
+		return
+	}
+
+	ap.stats.OpenConnections--
+	ap.wakeWaiter()
+	p.forgetRetired(addr, ap)
+	p.mu.Unlock()
+	conn.Close()
+}
+
+// update retires the connections to the addresses, which are not in addrs:
+// idle ones are closed at once, others once they are returned.
+func (p *pool) update(addrs []string) {
+	p.mu.Lock()
+
+	var conns []ClientConn
+	for addr, ap := range p.addrs {
+		ap.retired = !slices.Contains(addrs, addr)
+		if !ap.retired {
+			continue
+		}
+
+		for _, ic := range ap.idle {
+			if ic.timer != nil {
+				ic.timer.Stop()
+			}
+			conns = append(conns, ic.conn)
+		}
+		ap.stats.OpenConnections -= len(ap.idle)
+		ap.stats.Idle = 0
+		ap.idle = nil
+
+		p.forgetRetired(addr, ap)
+	}
+	p.mu.Unlock()
+
+	closeAll(conns)
+}
+
+// forgetRetired drops the pool of retired address once it has no
+// connections.
+func (p *pool) forgetRetired(addr string, ap *addrPool) {
+	if ap.retired && ap.stats.OpenConnections == 0 && len(ap.waiters) == 0 {
+		delete(p.addrs, addr)
+	}
+}
+
+// expire closes the connection, which was idle for too long.
+func (p *pool) expire(addr string, ic *idleConn) {
+	p.mu.Lock()
+	ap := p.addrPool(addr)
+	for i, other := range ap.idle {
+		if other == ic {
+			ap.idle = append(ap.idle[:i], ap.idle[i+1:]...)
+			ap.stats.Idle--
+			ap.stats.OpenConnections--
+			ap.stats.IdleTimeoutClosed++
+			ap.wakeWaiter()
+			p.mu.Unlock()
+			ic.conn.Close()
+			return
+		}
+	}
+	p.mu.Unlock()
+}
+
+func (p *pool) stats() map[string]PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make(map[string]PoolStats, len(p.addrs))
+	for addr, ap := range p.addrs {
+		stats[addr] = ap.stats
+	}
+
+	return stats
+}
+
+// close closes all idle connections. Connections in use are closed once
+// they are returned.
+func (p *pool) close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+
+	var conns []ClientConn
+	for _, ap := range p.addrs {
+		for _, ic := range ap.idle {
+			if ic.timer != nil {
+				ic.timer.Stop()
+			}
+			conns = append(conns, ic.conn)
+		}
+		ap.stats.OpenConnections -= len(ap.idle)
+		ap.stats.Idle = 0
+		ap.idle = nil
+
+		for range ap.waiters {
+			ap.wakeWaiter()
+		}
+	}
+	p.mu.Unlock()
+
+	return closeAll(conns)
+}
+
+func (ap *addrPool) wakeWaiter() {
+	if len(ap.waiters) == 0 {
+		return
+	}
+
+	ap.waiters[0] <- struct{}{}
+	ap.waiters = ap.waiters[1:]
+}
+
+func (ap *addrPool) removeWaiter(wait chan struct{}) {
+	for i, other := range ap.waiters {
+		if other == wait {
+			ap.waiters = append(ap.waiters[:i], ap.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func isHealthy(conn ClientConn) bool {
+	rc, ok := conn.(ReusableConn)
+	return ok && rc.Healthy()
+}
+
+func closeAll(conns []ClientConn) error {
+	var errs []error
+	for _, conn := range conns {
+		errs = append(errs, conn.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+type callConnsKey struct{}
+
+// callConns collects connections used by a single [Client.Call], so they are
+// returned to the pool once the response is handled.
+type callConns struct {
+	mu    sync.Mutex
+	conns []callConn
+}
+
+type callConn struct {
+	addr string
+	conn ClientConn
+	done func() // reports the end of the call to the balancer
+}
+
+func withCallConns(ctx context.Context) (context.Context, *callConns) {
+	cc := &callConns{}
+	return context.WithValue(ctx, callConnsKey{}, cc), cc
+}
+
+func (cc *callConns) add(addr string, conn ClientConn, done func()) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.conns = append(cc.conns, callConn{addr: addr, conn: conn, done: done})
+}
+
+// release returns all connections of the call to the pool.
+func (cc *callConns) release(p *pool, reuse bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	for _, c := range cc.conns {
+		p.put(c.addr, c.conn, reuse)
+		c.done()
+	}
+	cc.conns = nil
+}
@@ -0,0 +1,129 @@
+package srpc_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/testdata"
+)
+
+// countingConnector counts connections opened and closed through it.
+type countingConnector struct {
+	srpc.Connector
+	opened, closed atomic.Int64
+}
+
+func (cc *countingConnector) Connect(ctx context.Context, addr string) (srpc.ClientConn, error) {
+	conn, err := cc.Connector.Connect(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	cc.opened.Add(1)
+	return &countingConn{ReusableConn: conn.(srpc.ReusableConn), parent: cc}, nil
+}
+
+type countingConn struct {
+	srpc.ReusableConn
+	parent *countingConnector
+}
+
+func (c *countingConn) Close() error {
+	c.parent.closed.Add(1)
+	return c.ReusableConn.Close()
+}
+
+func TestPoolReuse(t *testing.T) {
+	ctx := t.Context()
+	server := srpctest.StartTestService(t)
+	addr := server.Addr
+
+	connector := &countingConnector{Connector: server.Cluster.NewPeer()}
+	c := srpc.NewClient(addr, codec.JSON, connector)
+	client := testdata.NewTestServiceClient(c)
+
+	for range 10 {
+		_, err := client.Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.NoError(t, err)
+		_, err = client.Divide(ctx, testdata.DivideReq{A: 1, B: 0})
+		require.Error(t, err)
+	}
+
+	require.EqualValues(t, 1, connector.opened.Load())
+	require.Equal(t, srpc.PoolStats{OpenConnections: 1, Idle: 1}, c.PoolStats()[addr])
+
+	require.NoError(t, c.Close())
+	require.EqualValues(t, 1, connector.closed.Load())
+
+	_, err := client.Add(ctx, testdata.AddReq{A: 1, B: 2})
+	require.ErrorIs(t, err, srpc.ErrClientClosed)
+}
+
+func TestPoolLimits(t *testing.T) {
+	ctx := t.Context()
+	server := srpctest.StartTestService(t)
+	addr := server.Addr
+
+	connector := &countingConnector{Connector: server.Cluster.NewPeer()}
+	c := srpc.NewClient(addr, codec.JSON, connector, srpc.WithPool(srpc.PoolConfig{
+		MaxIdle:     1,
+		MaxOpen:     3,
+		IdleTimeout: 50 * time.Millisecond,
+	}))
+	defer c.Close()
+	client := testdata.NewTestServiceClient(c)
+
+	// hold the connections with interceptor, so calls overlap
+	release := make(chan struct{})
+	var inFlight sync.WaitGroup
+	holding := srpc.NewClient(addr, codec.JSON, connector, srpc.WithPool(srpc.PoolConfig{MaxIdle: 1, MaxOpen: 3}),
+		srpc.WithUnaryClientInterceptors(func(ctx context.Context, req srpc.Request, invoke srpc.UnaryInvoker) (srpc.Response, error) {
+			resp, err := invoke(ctx, req)
+			inFlight.Done()
+			<-release
+			return resp, err
+		}),
+	)
+	defer holding.Close()
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		inFlight.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := testdata.NewTestServiceClient(holding).Add(ctx, testdata.AddReq{A: 1, B: 2})
+			require.NoError(t, err)
+		}()
+	}
+
+	// only 3 calls get connection, others wait
+	require.Eventually(t, func() bool {
+		s := holding.PoolStats()[addr]
+		return s.InUse == 3 && s.WaitCount == 2
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	inFlight.Wait()
+	wg.Wait()
+
+	s := holding.PoolStats()[addr]
+	require.Equal(t, 1, s.Idle)
+	require.Equal(t, 1, s.OpenConnections)
+	require.EqualValues(t, 2, s.MaxIdleClosed)
+
+	// idle connection is closed after timeout
+	_, err := client.Add(ctx, testdata.AddReq{A: 1, B: 2})
+	require.NoError(t, err)
+	require.Equal(t, 1, c.PoolStats()[addr].Idle)
+	require.Eventually(t, func() bool {
+		s := c.PoolStats()[addr]
+		return s.Idle == 0 && s.IdleTimeoutClosed == 1
+	}, time.Second, time.Millisecond)
+}
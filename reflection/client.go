@@ -0,0 +1,26 @@
+package reflection
+
+import (
+	"context"
+
+	"github.com/tymbaca/srpc"
+)
+
+func NewReflectionClient(client *srpc.Client) *ReflectionClient {
+	return &ReflectionClient{Client: client}
+}
+
+// ReflectionClient is the client of [Reflection] service.
+type ReflectionClient struct {
+	*srpc.Client
+}
+
+func (c *ReflectionClient) DescribeService(ctx context.Context, req DescribeServiceReq, opts ...srpc.CallOption) (resp DescribeServiceResp, err error) {
+	err = c.Client.Call(ctx, "Reflection.DescribeService", req, &resp, opts...)
+	return resp, err
+}
+
+func (c *ReflectionClient) ListServices(ctx context.Context, req ListServicesReq, opts ...srpc.CallOption) (resp ListServicesResp, err error) {
+	err = c.Client.Call(ctx, "Reflection.ListServices", req, &resp, opts...)
+	return resp, err
+}
@@ -0,0 +1,59 @@
+// Package reflection provides the service, which describes the services of
+// the server, so generic tooling (e.g. srpc-cli) can discover and call them:
+//
+//	srpc.Register(server, reflection.NewReflection(server))
+//
+// Register it after the other services, as the server must not be modified
+// while it is running.
+package reflection
+
+import (
+	"context"
+
+	"github.com/tymbaca/srpc"
+)
+
+type (
+	ListServicesReq  struct{}
+	ListServicesResp struct {
+		Services []string `json:"services"`
+	}
+
+	DescribeServiceReq struct {
+		Service string `json:"service"`
+	}
+	DescribeServiceResp struct {
+		Service srpc.ServiceDesc `json:"service"`
+	}
+)
+
+// Reflection is the reflection service of the server.
+type Reflection struct {
+	server *srpc.Server
+}
+
+func NewReflection(server *srpc.Server) *Reflection {
+	return &Reflection{server: server}
+}
+
+// ListServices returns the names of the registered services.
+func (r *Reflection) ListServices(ctx context.Context, req ListServicesReq) (ListServicesResp, error) {
+	resp := ListServicesResp{Services: []string{}}
+	for _, svc := range r.server.Services() {
+		resp.Services = append(resp.Services, svc.Name)
+	}
+
+	return resp, nil
+}
+
+// DescribeService returns the methods of the service and the schema of
+// their messages.
+func (r *Reflection) DescribeService(ctx context.Context, req DescribeServiceReq) (DescribeServiceResp, error) {
+	for _, svc := range r.server.Services() {
+		if svc.Name == req.Service {
+			return DescribeServiceResp{Service: svc}, nil
+		}
+	}
+
+	return DescribeServiceResp{}, srpc.Errorf(srpc.CodeNotFound, "unknown service %q", req.Service)
+}
@@ -0,0 +1,93 @@
+package reflection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/transport/inmem"
+	"github.com/tymbaca/srpc/transport/testdata"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestReflection(t *testing.T) {
+	ctx := t.Context()
+	cluster := inmem.New()
+	serverPeer := cluster.NewPeer()
+
+	server := testdata.NewTestServiceServer(srpc.NewServer(codec.JSON))
+	srpc.Register(server.Server, NewReflection(server.Server))
+	defer server.Close()
+	go server.Start(ctx, serverPeer.Listen())
+
+	client := srpc.NewClient(serverPeer.Addr(), codec.JSON, cluster.NewPeer())
+	defer client.Close()
+	c := NewReflectionClient(client)
+
+	t.Run("list services", func(t *testing.T) {
+		resp, err := c.ListServices(ctx, ListServicesReq{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Reflection", "TestService"}, resp.Services)
+	})
+
+	t.Run("describe service", func(t *testing.T) {
+		resp, err := c.DescribeService(ctx, DescribeServiceReq{Service: "TestService"})
+		require.NoError(t, err)
+
+		desc := resp.Service
+		require.Equal(t, "TestService", desc.Name)
+
+		var names, kinds []string
+		for _, m := range desc.Methods {
+			names = append(names, m.Name)
+			kinds = append(kinds, m.Kind)
+		}
+		require.Equal(t, []string{"Add", "Count", "Divide", "Echo", "Sum"}, names)
+		require.Equal(t, []string{"unary", "server-stream", "unary", "bidi-stream", "client-stream"}, kinds)
+
+		intType := srpc.TypeDesc{Name: "int", Kind: "int"}
+		require.Equal(t, srpc.TypeDesc{
+			Name: "github.com/tymbaca/srpc/transport/testdata.AddReq",
+			Kind: "struct",
+			Fields: []srpc.FieldDesc{
+				{Name: "A", Type: intType},
+				{Name: "B", Type: intType},
+			},
+		}, desc.Methods[0].Request)
+
+		// streamed messages are described
+		require.Equal(t, "github.com/tymbaca/srpc/transport/testdata.CountResp", desc.Methods[1].Response.Name)
+		require.Equal(t, "github.com/tymbaca/srpc/transport/testdata.SumReq", desc.Methods[4].Request.Name)
+		require.Equal(t, "github.com/tymbaca/srpc/transport/testdata.EchoMsg", desc.Methods[3].Request.Name)
+		require.Equal(t, "github.com/tymbaca/srpc/transport/testdata.EchoMsg", desc.Methods[3].Response.Name)
+	})
+
+	t.Run("describe itself", func(t *testing.T) {
+		resp, err := c.DescribeService(ctx, DescribeServiceReq{Service: "Reflection"})
+		require.NoError(t, err)
+
+		// recursive types are not expanded twice
+		describe := resp.Service.Methods[0]
+		require.Equal(t, "DescribeService", describe.Name)
+		methods := describe.Response.Fields[0].Type.Fields[1].Type
+		require.Equal(t, "slice", methods.Kind)
+
+		typeDesc := methods.Elem.Fields[2].Type // MethodDesc.Request
+		require.Equal(t, "github.com/tymbaca/srpc.TypeDesc", typeDesc.Name)
+		require.NotEmpty(t, typeDesc.Fields)
+
+		fieldDesc := typeDesc.Fields[2].Type.Elem // TypeDesc.Fields
+		require.Equal(t, "github.com/tymbaca/srpc.FieldDesc", fieldDesc.Name)
+		require.Equal(t, srpc.TypeDesc{Name: "github.com/tymbaca/srpc.TypeDesc", Kind: "struct"}, fieldDesc.Fields[2].Type)
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		_, err := c.DescribeService(ctx, DescribeServiceReq{Service: "Unknown"})
+		require.Equal(t, srpc.CodeNotFound, srpc.CodeOf(err))
+	})
+}
@@ -16,10 +16,16 @@ func (sm ServiceMethod) Split() (service string, method string, ok bool) {
 	return parts[0], parts[1], true
 }
 
+// Request is the call of the client. Its body can be streamed, i.e. still
+// written by the client while the server reads it and replies.
 type Request struct {
 	ServiceMethod ServiceMethod
 	Metadata      Metadata
 	Body          io.Reader // TODO: close?
+
+	// GetBody returns a new copy of Body, so the request can be retried. It
+	// is nil if the body can't be replayed, e.g. if it is streamed.
+	GetBody func() (io.ReadCloser, error)
 }
 
 type Response struct {
@@ -30,8 +36,6 @@ type Response struct {
 	Body          io.Reader // TODO: close?
 }
 
-type Metadata map[string][]string
-
 type StatusCode int
 
 func (s StatusCode) String() string {
@@ -50,6 +54,10 @@ func (s StatusCode) String() string {
 		return "StatusMethodNotFound"
 	case StatusInternalError:
 		return "StatusInternalError"
+	case StatusDeadlineExceeded:
+		return "StatusDeadlineExceeded"
+	case StatusResourceExhausted:
+		return "StatusResourceExhausted"
 	}
 
 	return ""
@@ -64,4 +72,26 @@ const (
 	StatusMethodNotFound
 	StatusBadRequest
 	StatusInternalError
+	StatusDeadlineExceeded
+	StatusResourceExhausted // call is rejected by the limits of the server, client should back off
 )
+
+// errorCode returns the code of the [Error] for the status of failed call.
+func (s StatusCode) errorCode() Code {
+	switch s {
+	case StatusOK:
+		return CodeOK
+	case StatusInvalidServiceMethod, StatusBadRequest:
+		return CodeInvalidArgument
+	case StatusServiceNotFound, StatusMethodNotFound:
+		return CodeUnimplemented
+	case StatusInternalError:
+		return CodeInternal
+	case StatusDeadlineExceeded:
+		return CodeDeadlineExceeded
+	case StatusResourceExhausted:
+		return CodeResourceExhausted
+	}
+
+	return CodeUnknown
+}
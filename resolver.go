@@ -0,0 +1,56 @@
+package srpc
+
+import (
+	"context"
+	"errors"
+)
+
+// Resolver produces the addresses of the backends and watches them, so
+// backends can be added or removed at runtime without recreating the
+// [Client]. See package resolver for implementations.
+type Resolver interface {
+	// Watch sends the full set of addresses every time it changes, starting
+	// with the current one. The channel is closed once ctx is done.
+	Watch(ctx context.Context) <-chan []string
+}
+
+// ErrNoAddrs is returned by the calls of the [Client], if its [Resolver]
+// has resolved an empty set of addresses.
+var ErrNoAddrs = errors.New("no addresses resolved")
+
+// resolvedAddrs tracks the readiness of the resolver.
+type resolvedAddrs struct {
+	ready chan struct{} // closed once the first set is received or the watch ends
+	err   error         // set before ready is closed, if no set was received
+}
+
+func newResolvedAddrs() *resolvedAddrs {
+	return &resolvedAddrs{ready: make(chan struct{})}
+}
+
+// watch passes the addresses from r to update until ctx is done. If the
+// watch ends before the first set, the waiting calls fail with [ErrNoAddrs].
+func (ra *resolvedAddrs) watch(ctx context.Context, r Resolver, update func(addrs []string)) {
+	first := true
+	for addrs := range r.Watch(ctx) {
+		update(addrs)
+		if first {
+			close(ra.ready)
+			first = false
+		}
+	}
+	if first {
+		ra.err = ErrNoAddrs
+		close(ra.ready)
+	}
+}
+
+// wait waits for the first set of addresses.
+func (ra *resolvedAddrs) wait(ctx context.Context) error {
+	select {
+	case <-ra.ready:
+		return ra.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
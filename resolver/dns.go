@@ -0,0 +1,76 @@
+package resolver
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tymbaca/srpc"
+)
+
+var _ srpc.Resolver = (*DNS)(nil)
+
+// DNS resolves the addresses from the SRV records of the service, e.g.
+// _rpc._tcp.example.com, and polls them for changes. Only the targets with
+// the lowest priority are resolved, the others are the backups used once
+// they are gone from the records. Weights of the records are not honoured,
+// the calls are spread over the targets by the [srpc.Balancer] of the client.
+type DNS struct {
+	service, proto, name string
+	interval             time.Duration
+
+	lookup func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// NewDNS returns the resolver of SRV records of the service. The records are
+// looked up every interval, zero means [DefaultInterval]. Lookup errors are
+// ignored, the last resolved addresses are kept until the next lookup.
+func NewDNS(service, proto, name string, interval time.Duration) *DNS {
+	return &DNS{
+		service:  service,
+		proto:    proto,
+		name:     name,
+		interval: interval,
+		lookup:   net.DefaultResolver.LookupSRV,
+	}
+}
+
+func (d *DNS) Watch(ctx context.Context) <-chan []string {
+	return poll(ctx, d.interval, d.resolve)
+}
+
+func (d *DNS) resolve(ctx context.Context) ([]string, error) {
+	_, srvs, err := d.lookup(ctx, d.service, d.proto, d.name)
+	if err != nil {
+		return nil, fmt.Errorf("lookup srv: %w", err)
+	}
+
+	return srvAddrs(srvs), nil
+}
+
+// srvAddrs returns the addresses of the records with the lowest priority,
+// ordered by target, so the set is stable across lookups.
+func srvAddrs(srvs []*net.SRV) []string {
+	srvs = slices.Clone(srvs)
+	slices.SortFunc(srvs, func(a, b *net.SRV) int {
+		return cmp.Or(
+			cmp.Compare(a.Priority, b.Priority),
+			cmp.Compare(a.Target, b.Target),
+			cmp.Compare(a.Port, b.Port),
+		)
+	})
+
+	addrs := make([]string, 0, len(srvs))
+	for _, srv := range srvs {
+		if srv.Priority != srvs[0].Priority {
+			break
+		}
+		addrs = append(addrs, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), fmt.Sprint(srv.Port)))
+	}
+
+	return addrs
+}
@@ -0,0 +1,57 @@
+package resolver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tymbaca/srpc"
+)
+
+var _ srpc.Resolver = (*File)(nil)
+
+// File resolves the addresses listed in the file, one per line. Empty lines
+// and lines starting with # are skipped. The file is watched for changes, so
+// the list can be edited at runtime.
+type File struct {
+	path     string
+	interval time.Duration
+}
+
+// NewFile returns the resolver of the addresses in the file at path. The
+// file is checked every interval, zero means [DefaultInterval]. If the file
+// can't be read, the last read addresses are kept.
+func NewFile(path string, interval time.Duration) *File {
+	return &File{path: path, interval: interval}
+}
+
+func (f *File) Watch(ctx context.Context) <-chan []string {
+	return poll(ctx, f.interval, f.resolve)
+}
+
+func (f *File) resolve(context.Context) ([]string, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAddrs(data), nil
+}
+
+func parseAddrs(data []byte) []string {
+	addrs := []string{}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addrs = append(addrs, line)
+	}
+
+	return addrs
+}
@@ -0,0 +1,46 @@
+package resolver
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+const DefaultInterval = 30 * time.Second
+
+// poll calls resolve every interval until ctx is done, sending the addresses
+// once they change. If resolve fails, the last set is kept.
+func poll(ctx context.Context, interval time.Duration, resolve func(ctx context.Context) ([]string, error)) <-chan []string {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ch := make(chan []string)
+	go func() {
+		defer close(ch)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		var last []string
+		for {
+			addrs, err := resolve(ctx)
+			if err == nil && (last == nil || !slices.Equal(addrs, last)) {
+				last = addrs
+				select {
+				case ch <- slices.Clone(addrs):
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
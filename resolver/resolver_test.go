@@ -0,0 +1,102 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func recv(t *testing.T, ch <-chan []string) []string {
+	t.Helper()
+
+	select {
+	case addrs := <-ch:
+		return addrs
+	case <-time.After(time.Second):
+		t.Fatal("no addresses received")
+		return nil
+	}
+}
+
+func TestStatic(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+
+	ch := Static{"a:1", "b:2"}.Watch(ctx)
+	require.Equal(t, []string{"a:1", "b:2"}, recv(t, ch))
+
+	cancel()
+	_, ok := <-ch
+	require.False(t, ok)
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addrs")
+	require.NoError(t, os.WriteFile(path, []byte("a:1\n\n# comment\n  b:2  \n"), 0o644))
+
+	ch := NewFile(path, 5*time.Millisecond).Watch(t.Context())
+	require.Equal(t, []string{"a:1", "b:2"}, recv(t, ch))
+
+	require.NoError(t, os.WriteFile(path, []byte("c:3\n"), 0o644))
+	require.Equal(t, []string{"c:3"}, recv(t, ch))
+
+	// the last set is kept, while the file is missing
+	require.NoError(t, os.Remove(path))
+	select {
+	case addrs := <-ch:
+		t.Fatalf("unexpected update for missing file: %v", addrs)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(""), 0o644))
+	require.Equal(t, []string{}, recv(t, ch))
+}
+
+func TestDNS(t *testing.T) {
+	var mu sync.Mutex
+	srvs := []*net.SRV{
+		{Target: "b.example.com.", Port: 2, Priority: 10},
+		{Target: "a.example.com.", Port: 1, Priority: 10},
+		{Target: "backup.example.com.", Port: 3, Priority: 20},
+	}
+	var lookupErr error
+
+	d := NewDNS("rpc", "tcp", "example.com", 5*time.Millisecond)
+	d.lookup = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		require.Equal(t, []string{"rpc", "tcp", "example.com"}, []string{service, proto, name})
+
+		mu.Lock()
+		defer mu.Unlock()
+		return "", srvs, lookupErr
+	}
+
+	ch := d.Watch(t.Context())
+	require.Equal(t, []string{"a.example.com:1", "b.example.com:2"}, recv(t, ch))
+
+	mu.Lock()
+	lookupErr = errors.New("temporary failure")
+	mu.Unlock()
+	select {
+	case addrs := <-ch:
+		t.Fatalf("unexpected update on lookup error: %v", addrs)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	mu.Lock()
+	lookupErr = nil
+	srvs = srvs[:1]
+	mu.Unlock()
+	require.Equal(t, []string{"b.example.com:2"}, recv(t, ch))
+
+	// the backup is used once the primary targets are gone
+	mu.Lock()
+	srvs = []*net.SRV{{Target: "backup.example.com.", Port: 3, Priority: 20}}
+	mu.Unlock()
+	require.Equal(t, []string{"backup.example.com:3"}, recv(t, ch))
+}
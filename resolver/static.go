@@ -0,0 +1,26 @@
+// Package resolver provides the implementations of [srpc.Resolver].
+package resolver
+
+import (
+	"context"
+	"slices"
+
+	"github.com/tymbaca/srpc"
+)
+
+var _ srpc.Resolver = Static{}
+
+// Static is the resolver of the fixed set of addresses.
+type Static []string
+
+func (s Static) Watch(ctx context.Context) <-chan []string {
+	ch := make(chan []string, 1)
+	ch <- slices.Clone(s)
+
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+
+	return ch
+}
@@ -0,0 +1,124 @@
+package srpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/inmem"
+)
+
+// chanResolver sends the addresses pushed to it.
+type chanResolver chan []string
+
+func (r chanResolver) Watch(ctx context.Context) <-chan []string {
+	ch := make(chan []string)
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case addrs := <-r:
+				select {
+				case ch <- addrs:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+func TestClientResolver(t *testing.T) {
+	ctx := t.Context()
+	cluster := inmem.New()
+	addrA := srpctest.Start(t, cluster, newReplica("a", 0), "Replica").Addr
+	addrB := srpctest.Start(t, cluster, newReplica("b", 0), "Replica").Addr
+
+	r := make(chanResolver)
+	c := srpc.NewClient("", codec.JSON, cluster.NewPeer(), srpc.WithResolver(r))
+	defer c.Close()
+
+	call := func() (string, error) {
+		var resp string
+		err := c.Call(ctx, "Replica.Get", "", &resp)
+		return resp, err
+	}
+
+	// calls wait for the first set
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	var resp string
+	require.ErrorIs(t, c.Call(waitCtx, "Replica.Get", "", &resp), context.DeadlineExceeded)
+
+	r <- []string{addrA}
+	resp, err := call()
+	require.NoError(t, err)
+	require.Equal(t, "a", resp)
+
+	// both addresses are used
+	r <- []string{addrA, addrB}
+	seen := map[string]bool{}
+	require.Eventually(t, func() bool {
+		resp, err := call()
+		if err != nil {
+			return false
+		}
+		seen[resp] = true
+		return seen["a"] && seen["b"]
+	}, time.Second, time.Millisecond)
+
+	// connections to the removed address are closed
+	r <- []string{addrB}
+	require.Eventually(t, func() bool {
+		_, ok := c.PoolStats()[addrA]
+		return !ok
+	}, time.Second, time.Millisecond)
+	for range 4 {
+		resp, err := call()
+		require.NoError(t, err)
+		require.Equal(t, "b", resp)
+	}
+
+	r <- []string{}
+	require.Eventually(t, func() bool {
+		_, err := call()
+		return err != nil
+	}, time.Second, time.Millisecond)
+	_, err = call()
+	require.ErrorIs(t, err, srpc.ErrNoAddrs)
+}
+
+// closedResolver closes its channel without sending any addresses.
+type closedResolver struct{}
+
+func (closedResolver) Watch(ctx context.Context) <-chan []string {
+	ch := make(chan []string)
+	close(ch)
+	return ch
+}
+
+func TestClientResolverClosedBeforeFirstSet(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+
+	c := srpc.NewClient("", codec.JSON, inmem.New().NewPeer(), srpc.WithResolver(closedResolver{}))
+	defer c.Close()
+
+	var resp string
+	err := c.Call(ctx, "Replica.Get", "", &resp)
+	require.ErrorIs(t, err, srpc.ErrNoAddrs)
+
+	// calls waiting on a closed client do not block until ctx is done
+	c = srpc.NewClient("", codec.JSON, inmem.New().NewPeer(), srpc.WithResolver(make(chanResolver)))
+	c.Close()
+	err = c.Call(ctx, "Replica.Get", "", &resp)
+	require.ErrorIs(t, err, srpc.ErrNoAddrs)
+}
@@ -0,0 +1,112 @@
+package srpc
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// RetryPolicy configures the retries of failed calls. Calls are retried only
+// if their request can be replayed (see [Request.GetBody]), so calls with
+// streamed requests are never retried. Calls are not retried once the
+// response is received, e.g. in the middle of the server stream.
+//
+// Retries should be enabled only for idempotent methods, as the failed
+// call may still be handled by the server.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first
+	// one. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. Zero means
+	// [DefaultInitialBackoff].
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. Zero means
+	// [DefaultMaxBackoff].
+	MaxBackoff time.Duration
+
+	// BackoffMultiplier is the factor, the delay is multiplied by after
+	// every attempt. Zero means [DefaultBackoffMultiplier].
+	BackoffMultiplier float64
+
+	// Jitter randomizes the delay by up to the fraction of it in both
+	// directions, e.g. 0.2 means ±20%. It must be within [0, 1].
+	Jitter float64
+
+	// RetryableStatuses are the statuses of the response, which are
+	// retried.
+	RetryableStatuses []StatusCode
+
+	// RetryTransportErrors enables retries of the calls, which failed to get
+	// the response, e.g. due to the lost connection. Cancellation of the
+	// call context is never retried.
+	RetryTransportErrors bool
+}
+
+const (
+	DefaultInitialBackoff    = 100 * time.Millisecond
+	DefaultMaxBackoff        = 5 * time.Second
+	DefaultBackoffMultiplier = 2
+)
+
+// backoff returns the delay before the retry, which follows attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	initial := tern(p.InitialBackoff > 0, p.InitialBackoff, DefaultInitialBackoff)
+	maxBackoff := tern(p.MaxBackoff > 0, p.MaxBackoff, DefaultMaxBackoff)
+	multiplier := tern(p.BackoffMultiplier > 0, p.BackoffMultiplier, DefaultBackoffMultiplier)
+
+	delay := float64(initial)
+	for range attempt - 1 {
+		delay *= multiplier
+		if delay >= float64(maxBackoff) {
+			break
+		}
+	}
+	delay = min(delay, float64(maxBackoff))
+
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+
+	return time.Duration(delay)
+}
+
+// retryable reports whether the attempt, which returned resp or err, can be
+// retried.
+func (p RetryPolicy) retryable(ctx context.Context, resp Response, err error) bool {
+	if err != nil {
+		return p.RetryTransportErrors && ctx.Err() == nil && !errors.Is(err, ErrClientClosed)
+	}
+
+	return slices.Contains(p.RetryableStatuses, resp.StatusCode)
+}
+
+// retryPolicy returns the policy of the method.
+func (c *Client) retryPolicy(serviceMethod ServiceMethod) RetryPolicy {
+	if p, ok := c.methodRetryPolicies[serviceMethod]; ok {
+		return p
+	}
+
+	return c.retry
+}
+
+// waitBackoff sleeps before the retry. It returns false if ctx is done
+// first or its deadline is too close to make another attempt.
+func waitBackoff(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return false
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
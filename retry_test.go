@@ -0,0 +1,138 @@
+package srpc_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/testdata"
+)
+
+// failFirstCalls returns the interceptor, which fails the first failures
+// calls with StatusInternalError, and the counter of the calls.
+func failFirstCalls(failures int64) (srpc.UnaryServerInterceptor, *atomic.Int64) {
+	var calls atomic.Int64
+	return func(ctx context.Context, req srpc.Request, next srpc.UnaryHandler) srpc.Response {
+		if calls.Add(1) <= failures {
+			return srpc.Response{
+				ServiceMethod: req.ServiceMethod,
+				StatusCode:    srpc.StatusInternalError,
+				Error:         srpc.Errorf(srpc.CodeUnavailable, "try again"),
+			}
+		}
+		return next(ctx, req)
+	}, &calls
+}
+
+var testRetryPolicy = srpc.RetryPolicy{
+	MaxAttempts:       3,
+	InitialBackoff:    time.Millisecond,
+	Jitter:            0.5,
+	RetryableStatuses: []srpc.StatusCode{srpc.StatusInternalError},
+}
+
+func TestRetry(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("retries until success", func(t *testing.T) {
+		flaky, calls := failFirstCalls(2)
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(flaky))
+		c := server.Client(t, srpc.WithRetryPolicy(testRetryPolicy))
+
+		resp, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.NoError(t, err)
+		require.Equal(t, 3, resp.Result)
+		require.EqualValues(t, 3, calls.Load())
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		flaky, calls := failFirstCalls(10)
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(flaky))
+		c := server.Client(t, srpc.WithRetryPolicy(testRetryPolicy))
+
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.Equal(t, srpc.CodeUnavailable, srpc.CodeOf(err))
+		require.EqualValues(t, 3, calls.Load())
+	})
+
+	t.Run("non-retryable status", func(t *testing.T) {
+		flaky, calls := failFirstCalls(0)
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(flaky))
+		c := server.Client(t, srpc.WithRetryPolicy(testRetryPolicy))
+
+		_, err := testdata.NewTestServiceClient(c).Divide(ctx, testdata.DivideReq{A: 1, B: 0})
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.EqualValues(t, 1, calls.Load())
+	})
+
+	t.Run("method override", func(t *testing.T) {
+		flaky, calls := failFirstCalls(10)
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(flaky))
+		c := server.Client(t,
+			srpc.WithRetryPolicy(testRetryPolicy),
+			srpc.WithMethodRetryPolicy("TestService.Add", srpc.RetryPolicy{MaxAttempts: 1}),
+		)
+
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.Error(t, err)
+		require.EqualValues(t, 1, calls.Load())
+	})
+
+	t.Run("streamed request is not retried", func(t *testing.T) {
+		flaky, calls := failFirstCalls(10)
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(flaky))
+		c := server.Client(t, srpc.WithRetryPolicy(testRetryPolicy))
+
+		stream := testdata.NewTestServiceClient(c).Sum(ctx)
+		_, err := stream.CloseAndRecv()
+		require.Equal(t, srpc.CodeUnavailable, srpc.CodeOf(err))
+		require.EqualValues(t, 1, calls.Load())
+	})
+
+	t.Run("honours deadline", func(t *testing.T) {
+		flaky, calls := failFirstCalls(10)
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(flaky))
+		policy := testRetryPolicy
+		policy.InitialBackoff = time.Second
+		c := server.Client(t, srpc.WithRetryPolicy(policy))
+
+		callCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := testdata.NewTestServiceClient(c).Add(callCtx, testdata.AddReq{A: 1, B: 2})
+		require.Equal(t, srpc.CodeUnavailable, srpc.CodeOf(err))
+		require.Less(t, time.Since(start), time.Second)
+		require.EqualValues(t, 1, calls.Load())
+	})
+
+	t.Run("transport errors", func(t *testing.T) {
+		flaky, calls := failFirstCalls(0)
+		server := srpctest.StartTestService(t, srpc.WithUnaryServerInterceptors(flaky))
+
+		var attempts atomic.Int64
+		failFirst := func(ctx context.Context, req srpc.Request, invoke srpc.UnaryInvoker) (srpc.Response, error) {
+			if attempts.Add(1) == 1 {
+				return srpc.Response{}, errors.New("connection reset")
+			}
+			return invoke(ctx, req)
+		}
+
+		policy := testRetryPolicy
+		policy.RetryTransportErrors = true
+		c := server.Client(t,
+			srpc.WithRetryPolicy(policy),
+			srpc.WithUnaryClientInterceptors(failFirst),
+		)
+
+		_, err := testdata.NewTestServiceClient(c).Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.NoError(t, err)
+		require.EqualValues(t, 2, attempts.Load())
+		require.EqualValues(t, 1, calls.Load())
+	})
+}
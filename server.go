@@ -6,17 +6,27 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/tymbaca/srpc/logger"
 	"github.com/tymbaca/srpc/pkg/pipe"
 )
 
+// ErrServerClosed is returned by [Server.Start] after [Server.Shutdown] or
+// [Server.Close].
+var ErrServerClosed = errors.New("server is closed")
+
 func NewServer(codec Codec, opts ...ServerOption) *Server {
 	s := &Server{
-		services: make(map[string]service),
-		codec:    codec,
-		logger:   logger.NoopLogger{},
+		services:   make(map[string]service),
+		codec:      codec,
+		logger:     logger.NoopLogger{},
+		shutdownCh: make(chan struct{}),
 	}
+	s.callsCtx, s.cancelCalls = context.WithCancel(context.Background())
 
 	for _, o := range opts {
 		o(s)
@@ -29,9 +39,21 @@ type Server struct {
 	codec    Codec
 	services map[string]service
 
-	l Listener
-
-	logger logger.Logger
+	mu         sync.Mutex
+	l          Listener
+	inShutdown bool
+	shutdownCh chan struct{} // closed once shutdown starts
+	onShutdown []func()
+
+	calls       sync.WaitGroup // calls in progress
+	callsCtx    context.Context
+	cancelCalls context.CancelFunc // force-closes calls in progress
+
+	logger       logger.Logger
+	interceptors []UnaryServerInterceptor
+	panicHandler PanicHandler
+	limits       limits
+	compression  CompressionConfig
 }
 
 type service struct {
@@ -43,7 +65,23 @@ type service struct {
 }
 
 type method struct {
-	val reflect.Value
+	val  reflect.Value
+	kind methodKind
+}
+
+type methodKind int
+
+const (
+	methodUnary        methodKind = iota // func(ctx, Req) (Resp, error)
+	methodServerStream                   // func(ctx, Req, *ServerStream[Resp]) error
+	methodClientStream                   // func(ctx, *ClientStream[Req]) (Resp, error)
+	methodBidiStream                     // func(ctx, *BidiStream[Req, Resp]) error
+)
+
+// streamsResponse reports whether the response of the method is the stream
+// of messages, rather than the complete body.
+func (k methodKind) streamsResponse() bool {
+	return k == methodServerStream || k == methodBidiStream
 }
 
 func Register[T any](s *Server, impl T) {
@@ -71,17 +109,31 @@ func RegisterWithName[T any](s *Server, impl T, name string) {
 	s.services[name] = service
 }
 
+// Start accepts the calls from l and handles each one in its own goroutine,
+// until l is closed or ctx is cancelled. Calls inherit ctx. After
+// [Server.Shutdown] or [Server.Close] Start returns [ErrServerClosed].
 func (s *Server) Start(ctx context.Context, l Listener) error {
+	s.mu.Lock()
+	if s.inShutdown {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
 	s.l = l
-	defer s.Close()
+	s.mu.Unlock()
+
+	defer s.closeListener()
 
 	for {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
 
-		conn, err := s.l.Accept()
+		conn, err := l.Accept()
 		if errors.Is(err, ErrListenerClosed) {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
 			return nil
 		}
 		if err != nil {
@@ -89,7 +141,20 @@ func (s *Server) Start(ctx context.Context, l Listener) error {
 			continue
 		}
 
+		if !s.trackCall() {
+			conn.Close()
+			return ErrServerClosed
+		}
+
+		release, err := s.limits.acquireServer()
 		go func() {
+			defer s.calls.Done()
+			if err != nil {
+				s.reject(conn, err)
+				return
+			}
+			defer release()
+
 			err := s.handleConn(ctx, conn)
 			if err != nil {
 				s.logger.Error(err.Error())
@@ -98,7 +163,65 @@ func (s *Server) Start(ctx context.Context, l Listener) error {
 	}
 }
 
+// Shutdown gracefully shuts down the server: it closes the listener, so no
+// new calls are accepted, and waits for the calls in progress to finish.
+// Handlers can learn about the shutdown with [ShutdownSignal] to finish
+// long-running calls, e.g. streams.
+//
+// If ctx expires first, Shutdown cancels the contexts of the remaining calls
+// and returns ctx's error, without waiting for the handlers to return.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.startShutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.calls.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		s.cancelCalls()
+		return ctx.Err()
+	}
+}
+
+// Close immediately closes the listener and cancels the contexts of all calls
+// in progress. For graceful shutdown use [Server.Shutdown].
 func (s *Server) Close() error {
+	err := s.startShutdown()
+	s.cancelCalls()
+
+	return err
+}
+
+// RegisterOnShutdown registers f to be called in its own goroutine, once
+// [Server.Shutdown] or [Server.Close] is called.
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onShutdown = append(s.onShutdown, f)
+}
+
+// startShutdown stops accepting new calls. It is called once, the subsequent
+// calls do nothing.
+func (s *Server) startShutdown() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inShutdown {
+		return nil
+	}
+
+	s.inShutdown = true
+	close(s.shutdownCh)
+	for _, f := range s.onShutdown {
+		go f()
+	}
+
 	if s.l != nil {
 		return s.l.Close()
 	}
@@ -106,10 +229,81 @@ func (s *Server) Close() error {
 	return nil
 }
 
+func (s *Server) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.inShutdown
+}
+
+// trackCall registers new call in progress, unless the server is shutting
+// down.
+func (s *Server) trackCall() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inShutdown {
+		return false
+	}
+
+	s.calls.Add(1)
+	return true
+}
+
+func (s *Server) closeListener() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.l != nil {
+		s.l.Close()
+	}
+}
+
+type shutdownKey struct{}
+
+// ShutdownSignal returns the channel, which is closed once the server starts
+// shutting down. It returns nil if ctx is not the context of the call.
+func ShutdownSignal(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(shutdownKey{}).(chan struct{})
+	return ch
+}
+
+// _rejectTimeout is the time the rejected call is given to receive the
+// reply, before its connection is closed.
+const _rejectTimeout = time.Second
+
+// reject replies to the call, which exceeds the server-wide concurrency
+// limit. The connection is closed once the reply takes longer than
+// [_rejectTimeout], so rejected calls don't pile up.
+func (s *Server) reject(conn ServerConn, err error) {
+	closeConn := sync.OnceFunc(func() { conn.Close() })
+	defer closeConn()
+
+	ctx, cancel := context.WithTimeout(conn.Context(), _rejectTimeout)
+	defer cancel()
+	context.AfterFunc(ctx, closeConn)
+
+	req := conn.Request()
+	if err := conn.Reply(ctx, respError(req, StatusResourceExhausted, "%w", err)); err != nil {
+		s.logger.Error(err.Error())
+	}
+}
+
 func (s *Server) handleConn(ctx context.Context, conn ServerConn) (err error) {
 	defer conn.Close()
 	req := conn.Request()
 
+	// handler must stop if client abandons the call or the server is
+	// force-closed
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(conn.Context(), cancel)
+	defer stop()
+	stopForce := context.AfterFunc(s.callsCtx, cancel)
+	defer stopForce()
+
+	ctx = context.WithValue(ctx, shutdownKey{}, s.shutdownCh)
+
 	serviceName, methodName, ok := req.ServiceMethod.Split()
 	if !ok {
 		return conn.Reply(ctx, respError(req, StatusInvalidServiceMethod, ""))
@@ -125,19 +319,193 @@ func (s *Server) handleConn(ctx context.Context, conn ServerConn) (err error) {
 		return conn.Reply(ctx, respError(req, StatusMethodNotFound, ""))
 	}
 
-	resp := s.call(method, ctx, req)
+	codec, contentType, err := s.callCodec(req.Metadata)
+	if err != nil {
+		return conn.Reply(ctx, respError(req, StatusBadRequest, "%w", err))
+	}
+
+	req.Body, err = decompressBody(req.Body, req.Metadata)
+	if err != nil {
+		return conn.Reply(ctx, respError(req, StatusBadRequest, "%w", err))
+	}
+
+	release, err := s.limits.acquire(req.ServiceMethod, req.Metadata)
+	if err != nil {
+		return conn.Reply(ctx, respError(req, StatusResourceExhausted, "%w", err))
+	}
+	defer release()
+
+	timeout, hasTimeout, err := parseTimeout(req.Metadata)
+	if err != nil {
+		return conn.Reply(ctx, respError(req, StatusBadRequest, "%w", err))
+	}
+	if hasTimeout && timeout <= 0 {
+		return conn.Reply(ctx, respError(req, StatusDeadlineExceeded, "%s", errDeadlineExceeded.Message))
+	}
+
+	callCtx := NewIncomingContext(ctx, req.Metadata)
+	callCtx, header := withResponseHeader(callCtx)
+	callCtx, trailer := withResponseTrailer(callCtx)
+	if hasTimeout {
+		// handler must stop once the client gives up, but the reply is
+		// sent anyway
+		var cancelTimeout context.CancelFunc
+		callCtx, cancelTimeout = context.WithTimeoutCause(callCtx, timeout, errDeadlineExceeded)
+		defer cancelTimeout()
+	}
 
-	return conn.Reply(ctx, resp)
+	handler := chainServerInterceptors(s.interceptors, func(ctx context.Context, req Request) Response {
+		return s.call(method, codec, ctx, req)
+	})
+	resp := s.handle(handler, callCtx, req)
+
+	if resp.Metadata == nil {
+		resp.Metadata = Metadata{}
+	}
+	resp.Metadata.merge(header.metadata())
+	if resp.StatusCode != StatusOK || !method.kind.streamsResponse() {
+		// streamed response sends the trailer at the end of the body
+		addTrailer(resp.Metadata, trailer.metadata())
+	}
+	if contentType != "" {
+		resp.Metadata.Set(ContentTypeKey, contentType)
+	}
+	if resp.StatusCode == StatusOK && resp.Body != nil {
+		resp = s.compressResponse(method, req, resp)
+	}
+
+	if resp.Body == nil {
+		return conn.Reply(ctx, resp)
+	}
+
+	// transport may return from Reply before the body is read (e.g. inmem),
+	// handler's context must live until then
+	body := newWatchedBody(resp.Body)
+	resp.Body = body
+	defer body.Close()
+
+	if err := conn.Reply(ctx, resp); err != nil {
+		return err
+	}
+
+	select {
+	case <-body.done:
+	case <-ctx.Done():
+	}
+
+	return nil
 }
 
-func (s *Server) call(m method, ctx context.Context, req Request) Response {
-	// TODO: put metadata in context
+// handle calls the handler, recovering its panic.
+func (s *Server) handle(handler UnaryHandler, ctx context.Context, req Request) (resp Response) {
+	defer func() {
+		if p := recover(); p != nil {
+			resp = Response{
+				ServiceMethod: req.ServiceMethod,
+				Metadata:      Metadata{},
+				StatusCode:    StatusInternalError,
+				Error:         s.handlePanic(ctx, req, p),
+			}
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// handlePanic logs and reports the recovered panic and returns the error for
+// the client. It must be called from the deferred function, so the stack
+// trace contains the panicking frame.
+func (s *Server) handlePanic(ctx context.Context, req Request, p any) *Error {
+	stack := debug.Stack()
+	s.logger.Error("panic in handler", "method", req.ServiceMethod, "panic", p, "stack", string(stack))
+	if s.panicHandler != nil {
+		s.panicHandler(ctx, req, p, stack)
+	}
+
+	return Errorf(CodeInternal, "panic in %s", req.ServiceMethod)
+}
+
+// compressResponse compresses the body of the response with the compressor
+// negotiated with the client, see [CompressionConfig].
+func (s *Server) compressResponse(m method, req Request, resp Response) Response {
+	name, comp, ok := s.responseCompressor(req)
+	if !ok {
+		return resp
+	}
+
+	body, ok := resp.Body.(io.ReadCloser)
+	if !ok {
+		body = io.NopCloser(resp.Body)
+	}
+
+	body, compressed, err := compressBody(body, comp, s.compression.minSize(), !m.kind.streamsResponse())
+	if err != nil {
+		body.Close()
+		errResp := respError(req, StatusInternalError, "encode response: %w", err)
+		errResp.Metadata = resp.Metadata
+		return errResp
+	}
+
+	resp.Body = body
+	if compressed {
+		resp.Metadata.Set(EncodingKey, name)
+	}
+
+	return resp
+}
+
+// responseCompressor returns the compressor of the response: the one of the
+// server config or the one of the request, whichever the client accepts.
+func (s *Server) responseCompressor(req Request) (string, Compressor, bool) {
+	if s.compression.disabled(req.ServiceMethod) {
+		return "", nil, false
+	}
+
+	accept := req.Metadata.Get(AcceptEncodingKey)
+	candidates := append([]string{s.compression.Compressor}, req.Metadata.Get(EncodingKey)...)
+	for _, name := range candidates {
+		if name == "" || !slices.Contains(accept, name) {
+			continue
+		}
+		if c, ok := CompressorByName(name); ok {
+			return name, c, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// callCodec returns the codec of the call, named by [ContentTypeKey] of the
+// request, or the codec of the server, if the request doesn't name one.
+func (s *Server) callCodec(md Metadata) (codec Codec, contentType string, err error) {
+	vals := md.Get(ContentTypeKey)
+	if len(vals) == 0 || vals[0] == "" {
+		return s.codec, "", nil
+	}
+
+	codec, ok := CodecByName(vals[0])
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported codec %q", vals[0])
+	}
+
+	return codec, vals[0], nil
+}
+
+func (s *Server) call(m method, codec Codec, ctx context.Context, req Request) Response {
+	switch m.kind {
+	case methodServerStream:
+		return s.callServerStream(m, codec, ctx, req)
+	case methodClientStream:
+		return s.callClientStream(m, codec, ctx, req)
+	case methodBidiStream:
+		return s.callBidiStream(m, codec, ctx, req)
+	}
 
 	assert(m.val.Type().NumIn() == 2)
 	assert(m.val.Type().In(0) == reflect.TypeFor[context.Context]())
 
 	argVal := reflect.New(m.val.Type().In(1))
-	err := s.codec.Decode(req.Body, argVal.Interface())
+	err := decodeBuffered(codec, req.Body, argVal.Interface())
 	if err != nil {
 		return respError(req, StatusBadRequest, "can't decode: %w", err)
 	}
@@ -146,16 +514,137 @@ func (s *Server) call(m method, ctx context.Context, req Request) Response {
 	// assert(len(retVals) == 2)
 	// assert(reflect.TypeOf(retVals[1]) == reflect.TypeFor[error]())
 
+	return s.unaryResult(ctx, codec, req, retVals)
+}
+
+// unaryResult builds the response from the results of the method, which
+// returns (Resp, error). If the deadline of the client is exceeded, the
+// results are dropped, the client won't wait for them anyway.
+func (s *Server) unaryResult(ctx context.Context, codec Codec, req Request, retVals []reflect.Value) Response {
+	if deadlineExceeded(ctx) {
+		return respError(req, StatusDeadlineExceeded, "%s", errDeadlineExceeded.Message)
+	}
+
 	ret := retVals[0].Interface()
 	if !retVals[1].IsNil() {
-		return respError(req, StatusErrorFromService, "error from service: %w", retVals[1].Interface().(error))
+		return Response{
+			ServiceMethod: req.ServiceMethod,
+			Metadata:      Metadata{},
+			StatusCode:    StatusErrorFromService,
+			Error:         toError(retVals[1].Interface().(error), CodeUnknown),
+		}
 	}
 
-	return resp(req, StatusOK, pipe.ToReader(func(w io.Writer) error {
-		return s.codec.Encode(w, ret)
+	body, err := encodeBuffered(codec, ret)
+	if err != nil {
+		return respError(req, StatusInternalError, "encode response: %w", err)
+	}
+
+	return resp(req, StatusOK, body)
+}
+
+func (s *Server) callServerStream(m method, codec Codec, ctx context.Context, req Request) Response {
+	typ := m.val.Type()
+	assert(typ.NumIn() == 3)
+
+	argVal := reflect.New(typ.In(1))
+	err := decodeBuffered(codec, req.Body, argVal.Interface())
+	if err != nil {
+		return respError(req, StatusBadRequest, "can't decode: %w", err)
+	}
+
+	// method is called while the body is being read, so it is not blocked
+	// by the transport while sending the messages
+	return resp(req, StatusOK, pipe.ToReader(func(w io.Writer) (err error) {
+		// the method runs after the response is returned, so the panic is
+		// sent as the last message
+		defer func() {
+			if p := recover(); p != nil {
+				err = finishStream(ctx, w, s.handlePanic(ctx, req, p))
+			}
+		}()
+
+		st := &stream{
+			ctx:   ctx,
+			codec: codec,
+			send:  func(msg any) error { return sendMessage(w, codec, msg) },
+		}
+
+		retVals := m.val.Call([]reflect.Value{reflect.ValueOf(ctx), argVal.Elem(), newStreamValue(typ.In(2), st)})
+		if !retVals[0].IsNil() {
+			return finishStream(ctx, w, streamError(ctx, retVals[0].Interface().(error)))
+		}
+
+		return finishStream(ctx, w, nil)
+	}))
+}
+
+// finishStream sends the trailer of the call and the error of the method,
+// if any, as the last frames of the streamed response.
+func finishStream(ctx context.Context, w io.Writer, err *Error) error {
+	if trailer := responseTrailer(ctx); len(trailer) > 0 {
+		if err := sendTrailer(w, trailer); err != nil {
+			return err
+		}
+	}
+
+	if err != nil {
+		return sendError(w, err)
+	}
+
+	return nil
+}
+
+func (s *Server) callClientStream(m method, codec Codec, ctx context.Context, req Request) Response {
+	typ := m.val.Type()
+	assert(typ.NumIn() == 2)
+
+	st := &stream{
+		ctx:   ctx,
+		codec: codec,
+		recv:  func(dst any) error { return recvMessage(req.Body, codec, dst, nil) },
+	}
+
+	retVals := m.val.Call([]reflect.Value{reflect.ValueOf(ctx), newStreamValue(typ.In(1), st)})
+	return s.unaryResult(ctx, codec, req, retVals)
+}
+
+func (s *Server) callBidiStream(m method, codec Codec, ctx context.Context, req Request) Response {
+	typ := m.val.Type()
+	assert(typ.NumIn() == 2)
+
+	return resp(req, StatusOK, pipe.ToReader(func(w io.Writer) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = finishStream(ctx, w, s.handlePanic(ctx, req, p))
+			}
+		}()
+
+		st := &stream{
+			ctx:   ctx,
+			codec: codec,
+			send:  func(msg any) error { return sendMessage(w, codec, msg) },
+			recv:  func(dst any) error { return recvMessage(req.Body, codec, dst, nil) },
+		}
+
+		retVals := m.val.Call([]reflect.Value{reflect.ValueOf(ctx), newStreamValue(typ.In(1), st)})
+		if !retVals[0].IsNil() {
+			return finishStream(ctx, w, streamError(ctx, retVals[0].Interface().(error)))
+		}
+
+		return finishStream(ctx, w, nil)
 	}))
 }
 
+// streamError returns the error of the streaming method for the client.
+func streamError(ctx context.Context, err error) *Error {
+	if deadlineExceeded(ctx) {
+		return errDeadlineExceeded
+	}
+
+	return toError(err, CodeUnknown)
+}
+
 func resp(req Request, statusCode StatusCode, body io.Reader) Response {
 	resp := Response{
 		ServiceMethod: req.ServiceMethod,
@@ -169,11 +658,13 @@ func resp(req Request, statusCode StatusCode, body io.Reader) Response {
 }
 
 func respError(req Request, statusCode StatusCode, errorMsg string, errorMsgArgs ...any) Response {
+	err := tern(errorMsg != "", fmt.Errorf(errorMsg, errorMsgArgs...), fmt.Errorf("code: %s", statusCode))
+
 	resp := Response{
 		ServiceMethod: req.ServiceMethod,
 		Metadata:      Metadata{},
 		StatusCode:    statusCode,
-		Error:         tern(errorMsg != "", fmt.Errorf(errorMsg, errorMsgArgs...), fmt.Errorf("code: %s", statusCode)),
+		Error:         &Error{Code: statusCode.errorCode(), Message: err.Error()},
 		Body:          nil,
 	}
 
@@ -186,33 +677,35 @@ func getMethods(v reflect.Value) map[string]method {
 		m := v.Method(i)
 		name := v.Type().Method(i).Name
 
-		if isSuitableMethod(m) {
-			methods[name] = method{val: m}
+		if kind, ok := suitableMethodKind(m); ok {
+			methods[name] = method{val: m, kind: kind}
 		}
 	}
 
 	return methods
 }
 
-func isSuitableMethod(method reflect.Value) bool {
+func suitableMethodKind(method reflect.Value) (methodKind, bool) {
 	typ := method.Type()
-	if typ.NumIn() != 2 {
-		return false
+	if typ.NumIn() < 2 || typ.In(0) != reflect.TypeFor[context.Context]() {
+		return 0, false
 	}
 
-	if typ.In(0) != reflect.TypeFor[context.Context]() {
-		return false
+	returnsErr := typ.NumOut() == 1 && typ.Out(0) == reflect.TypeFor[error]()
+	returnsResp := typ.NumOut() == 2 && typ.Out(1) == reflect.TypeFor[error]()
+
+	switch {
+	case typ.NumIn() == 2 && isStreamType(typ.In(1), "ClientStream") && returnsResp:
+		return methodClientStream, true
+	case typ.NumIn() == 2 && isStreamType(typ.In(1), "BidiStream") && returnsErr:
+		return methodBidiStream, true
+	case typ.NumIn() == 2 && returnsResp:
+		return methodUnary, true
+	case typ.NumIn() == 3 && isStreamType(typ.In(2), "ServerStream") && returnsErr:
+		return methodServerStream, true
 	}
 
-	if typ.NumOut() != 2 {
-		return false
-	}
-
-	if typ.Out(1) != reflect.TypeFor[error]() {
-		return false
-	}
-
-	return true
+	return 0, false
 }
 
 func toValues(ins ...any) []reflect.Value {
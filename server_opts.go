@@ -1,6 +1,10 @@
 package srpc
 
-import "github.com/tymbaca/srpc/logger"
+import (
+	"context"
+
+	"github.com/tymbaca/srpc/logger"
+)
 
 type ServerOption func(s *Server)
 
@@ -9,3 +13,59 @@ func WithLogger(logger logger.Logger) ServerOption {
 		s.logger = logger
 	}
 }
+
+// WithUnaryServerInterceptors appends interceptors to the server's chain.
+// Interceptors are called in the provided order, the first one being the
+// outermost.
+func WithUnaryServerInterceptors(interceptors ...UnaryServerInterceptor) ServerOption {
+	return func(s *Server) {
+		s.interceptors = append(s.interceptors, interceptors...)
+	}
+}
+
+// PanicHandler is called with the recovered value and the stack trace, when
+// the method or an interceptor panics, e.g. to report the panic. It is
+// called after the panic is logged, the client receives
+// [StatusInternalError] anyway.
+type PanicHandler func(ctx context.Context, req Request, p any, stack []byte)
+
+// WithPanicHandler sets the handler of the recovered panics.
+func WithPanicHandler(h PanicHandler) ServerOption {
+	return func(s *Server) {
+		s.panicHandler = h
+	}
+}
+
+// WithMaxConcurrentCalls limits the number of calls handled at once. Excess
+// calls are rejected with [StatusResourceExhausted].
+func WithMaxConcurrentCalls(n int) ServerOption {
+	return func(s *Server) {
+		s.limits.setConcurrency("", n)
+	}
+}
+
+// WithConcurrencyLimit limits the number of calls to the service ("Service")
+// or the method ("Service.Method") handled at once. Excess calls are
+// rejected with [StatusResourceExhausted].
+func WithConcurrencyLimit(name string, n int) ServerOption {
+	return func(s *Server) {
+		s.limits.setConcurrency(name, n)
+	}
+}
+
+// WithRateLimit limits the rate of the calls to the service ("Service"),
+// the method ("Service.Method") or all calls (""). Excess calls are
+// rejected with [StatusResourceExhausted].
+func WithRateLimit(name string, limit RateLimit) ServerOption {
+	return func(s *Server) {
+		s.limits.setRate(name, limit)
+	}
+}
+
+// WithServerCompression configures the compression of the responses, see
+// [CompressionConfig]. Compressed requests are handled regardless of it.
+func WithServerCompression(cfg CompressionConfig) ServerOption {
+	return func(s *Server) {
+		s.compression = cfg
+	}
+}
@@ -0,0 +1,262 @@
+package srpc_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/transport/inmem"
+)
+
+// blockingService blocks the calls until release is closed.
+type blockingService struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingService) Block(ctx context.Context, req string) (string, error) {
+	s.started <- struct{}{}
+	select {
+	case <-s.release:
+		return req, nil
+	case <-srpc.ShutdownSignal(ctx):
+		return "shutdown", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Ignore blocks the calls until release is closed, ignoring the shutdown
+// signal.
+func (s *blockingService) Ignore(ctx context.Context, req string) (string, error) {
+	s.started <- struct{}{}
+	select {
+	case <-s.release:
+		return req, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Deadline returns the time left until the deadline of the call.
+func (s *blockingService) Deadline(ctx context.Context, req string) (time.Duration, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, errors.New("no deadline")
+	}
+	return time.Until(deadline), nil
+}
+
+func newBlockingService() *blockingService {
+	return &blockingService{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func TestServerShutdown(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("waits for calls in progress", func(t *testing.T) {
+		svc := newBlockingService()
+		server := srpctest.Start(t, nil, svc, "Blocking")
+		client := server.Client(t)
+
+		callErr := make(chan error, 1)
+		go func() {
+			var resp string
+			err := client.Call(ctx, "Blocking.Ignore", "done", &resp)
+			if err == nil && resp != "done" {
+				err = errors.New("unexpected response: " + resp)
+			}
+			callErr <- err
+		}()
+		<-svc.started
+
+		shutdownErr := make(chan error, 1)
+		go func() { shutdownErr <- server.Shutdown(ctx) }()
+
+		require.ErrorIs(t, <-server.Done, srpc.ErrServerClosed)
+		select {
+		case err := <-shutdownErr:
+			t.Fatalf("shutdown returned before the call finished: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		// new calls are rejected
+		var resp string
+		require.Error(t, client.Call(ctx, "Blocking.Ignore", "new", &resp))
+
+		close(svc.release)
+		require.NoError(t, <-callErr)
+		require.NoError(t, <-shutdownErr)
+	})
+
+	t.Run("signals handlers", func(t *testing.T) {
+		svc := newBlockingService()
+		server := srpctest.Start(t, nil, svc, "Blocking")
+		client := server.Client(t)
+
+		callErr := make(chan error, 1)
+		var resp string
+		go func() { callErr <- client.Call(ctx, "Blocking.Block", "done", &resp) }()
+		<-svc.started
+
+		require.NoError(t, server.Shutdown(ctx))
+		require.NoError(t, <-callErr)
+		require.Equal(t, "shutdown", resp)
+	})
+
+	t.Run("force-closes at deadline", func(t *testing.T) {
+		svc := newBlockingService()
+		server := srpctest.Start(t, nil, svc, "Blocking")
+		client := server.Client(t)
+
+		callErr := make(chan error, 1)
+		go func() {
+			var resp string
+			callErr <- client.Call(ctx, "Blocking.Ignore", "done", &resp)
+		}()
+		<-svc.started
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, server.Shutdown(shutdownCtx), context.DeadlineExceeded)
+
+		// handler context is cancelled
+		require.Error(t, <-callErr)
+	})
+
+	t.Run("start after shutdown", func(t *testing.T) {
+		server := srpc.NewServer(codec.JSON)
+		require.NoError(t, server.Shutdown(ctx))
+		require.ErrorIs(t, server.Start(ctx, inmem.New().NewPeer().Listen()), srpc.ErrServerClosed)
+	})
+
+	t.Run("on shutdown hooks", func(t *testing.T) {
+		server := srpc.NewServer(codec.JSON)
+		called := make(chan struct{})
+		server.RegisterOnShutdown(func() { close(called) })
+		require.NoError(t, server.Close())
+
+		select {
+		case <-called:
+		case <-time.After(time.Second):
+			t.Fatal("hook was not called")
+		}
+	})
+}
+
+func TestServerDeadline(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("handler gets client deadline", func(t *testing.T) {
+		client := srpctest.Start(t, nil, newBlockingService(), "Blocking").Client(t)
+
+		callCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		var left time.Duration
+		require.NoError(t, client.Call(callCtx, "Blocking.Deadline", "", &left))
+		require.Greater(t, left, time.Duration(0))
+		require.LessOrEqual(t, left, time.Second)
+	})
+
+	t.Run("no deadline", func(t *testing.T) {
+		client := srpctest.Start(t, nil, newBlockingService(), "Blocking").Client(t)
+
+		var left time.Duration
+		err := client.Call(ctx, "Blocking.Deadline", "", &left)
+		require.ErrorContains(t, err, "no deadline")
+	})
+
+	// requests are sent with raw conns, so the client doesn't give up
+	// before the server
+	do := func(t *testing.T, method srpc.ServiceMethod, timeout string) srpc.Response {
+		svc := &blockingService{started: make(chan struct{}, 1), release: make(chan struct{})}
+		server := srpctest.Start(t, nil, svc, "Blocking")
+
+		conn, err := server.Cluster.NewPeer().Connect(ctx, server.Addr)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		resp, err := conn.Do(ctx, srpc.Request{
+			ServiceMethod: method,
+			Metadata:      srpc.Pairs(srpc.TimeoutKey, timeout),
+			Body:          strings.NewReader(`"req"`),
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("exceeded in handler", func(t *testing.T) {
+		resp := do(t, "Blocking.Ignore", "20ms")
+		require.Equal(t, srpc.StatusDeadlineExceeded, resp.StatusCode)
+		require.Equal(t, srpc.CodeDeadlineExceeded, srpc.CodeOf(resp.Error))
+	})
+
+	t.Run("exceeded before call", func(t *testing.T) {
+		resp := do(t, "Blocking.Ignore", "-1ms")
+		require.Equal(t, srpc.StatusDeadlineExceeded, resp.StatusCode)
+	})
+
+	t.Run("invalid timeout", func(t *testing.T) {
+		resp := do(t, "Blocking.Ignore", "soon")
+		require.Equal(t, srpc.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+type panickingService struct{}
+
+func (panickingService) Unary(ctx context.Context, req string) (string, error) {
+	panic("unary")
+}
+
+func (panickingService) Stream(ctx context.Context, req string, stream *srpc.ServerStream[string]) error {
+	if err := stream.Send(req); err != nil {
+		return err
+	}
+	panic("stream")
+}
+
+func TestServerPanicRecovery(t *testing.T) {
+	ctx := t.Context()
+	panics := make(chan any, 2)
+	server := srpctest.Start(t, nil, panickingService{}, "Panicking", srpc.WithPanicHandler(func(ctx context.Context, req srpc.Request, p any, stack []byte) {
+		require.NotEmpty(t, stack)
+		panics <- p
+	}))
+	client := server.Client(t)
+
+	t.Run("unary", func(t *testing.T) {
+		var resp string
+		err := client.Call(ctx, "Panicking.Unary", "req", &resp)
+		require.Equal(t, srpc.CodeInternal, srpc.CodeOf(err))
+		require.Equal(t, "unary", <-panics)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var msgs []string
+		var err error
+		for msg, recvErr := range srpc.CallServerStream[string](ctx, client, "Panicking.Stream", "req") {
+			if recvErr != nil {
+				err = recvErr
+				break
+			}
+			msgs = append(msgs, msg)
+		}
+		require.Equal(t, []string{"req"}, msgs)
+		require.Equal(t, srpc.CodeInternal, srpc.CodeOf(err))
+		require.Equal(t, "stream", <-panics)
+	})
+
+	t.Run("server keeps serving", func(t *testing.T) {
+		var resp string
+		err := client.Call(ctx, "Panicking.Unary", "req", &resp)
+		require.Equal(t, srpc.CodeInternal, srpc.CodeOf(err))
+		<-panics
+	})
+}
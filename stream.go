@@ -0,0 +1,217 @@
+package srpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// stream is the underlying type of all generic stream types, so they can be
+// created with reflection for any type parameters, see [newStreamValue].
+type stream struct {
+	ctx   context.Context
+	codec Codec
+
+	send func(msg any) error
+	recv func(dst any) error
+}
+
+// ServerStream is the stream of responses of the server-streaming method:
+//
+//	func (s *Service) List(ctx context.Context, req ListReq, stream *srpc.ServerStream[Item]) error
+//
+// The stream is closed once the method returns. If the method returns an
+// error, client receives it after all sent messages.
+type ServerStream[T any] stream
+
+// Send sends msg to the client. It blocks until the transport takes it.
+func (s *ServerStream[T]) Send(msg T) error {
+	return s.send(msg)
+}
+
+// Context returns the context of the call.
+func (s *ServerStream[T]) Context() context.Context {
+	return s.ctx
+}
+
+// ClientStream is the stream of requests of the client-streaming method:
+//
+//	func (s *Service) Upload(ctx context.Context, stream *srpc.ClientStream[Chunk]) (UploadResp, error)
+type ClientStream[T any] stream
+
+// Recv receives the next message from the client. It returns [io.EOF] once
+// the client closes its side of the stream.
+func (s *ClientStream[T]) Recv() (T, error) {
+	var msg T
+	err := s.recv(&msg)
+	return msg, err
+}
+
+// Context returns the context of the call.
+func (s *ClientStream[T]) Context() context.Context {
+	return s.ctx
+}
+
+// BidiStream is the stream of the bidirectional streaming method, which
+// receives requests and sends responses independently:
+//
+//	func (s *Service) Chat(ctx context.Context, stream *srpc.BidiStream[Message, Message]) error
+//
+// The stream is closed once the method returns. If the method returns an
+// error, client receives it after all sent messages.
+type BidiStream[Req, Resp any] stream
+
+// Recv receives the next message from the client. It returns [io.EOF] once
+// the client closes its side of the stream.
+func (s *BidiStream[Req, Resp]) Recv() (Req, error) {
+	var msg Req
+	err := s.recv(&msg)
+	return msg, err
+}
+
+// Send sends msg to the client. It blocks until the transport takes it.
+func (s *BidiStream[Req, Resp]) Send(msg Resp) error {
+	return s.send(msg)
+}
+
+// Context returns the context of the call.
+func (s *BidiStream[Req, Resp]) Context() context.Context {
+	return s.ctx
+}
+
+// newStreamValue creates the value of generic stream type typ (e.g.
+// *ServerStream[Item]) with the provided implementation.
+func newStreamValue(typ reflect.Type, s *stream) reflect.Value {
+	return reflect.ValueOf(s).Convert(typ)
+}
+
+// isStreamType reports whether typ is a pointer to the generic stream type
+// with provided name, e.g. *ServerStream[Item].
+func isStreamType(typ reflect.Type, name string) bool {
+	if typ.Kind() != reflect.Pointer || !reflect.TypeFor[*stream]().ConvertibleTo(typ) {
+		return false
+	}
+
+	elem := typ.Elem()
+	return elem.PkgPath() == reflect.TypeFor[stream]().PkgPath() && strings.HasPrefix(elem.Name(), name+"[")
+}
+
+// Streamed messages are sent in the body of request or response as
+// frames:
+//
+//	| kind (1 byte) | payload length (4 bytes, big endian) | payload |
+//
+// Payload of [messageData] is encoded with the codec, payload of
+// [messageError] is encoded with [MarshalError]. [messageError] is the last
+// frame of the stream, returned by the method. [messageTrailer] carries the
+// JSON encoded trailer of the response, see [SetTrailer], it is sent once the
+// method returns, before [messageError].
+type messageKind byte
+
+const (
+	messageData messageKind = iota
+	messageError
+	messageTrailer
+)
+
+const _messageHeaderSize = 5
+
+const _maxMessageSize = 64 << 20
+
+func writeMessage(w io.Writer, kind messageKind, payload []byte) error {
+	var hdr [_messageHeaderSize]byte
+	hdr[0] = byte(kind)
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(payload)))
+
+	if _, err := w.Write(append(hdr[:], payload...)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func encodeMessage(codec Codec, msg any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, msg); err != nil {
+		return nil, fmt.Errorf("encode message: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func sendMessage(w io.Writer, codec Codec, msg any) error {
+	payload, err := encodeMessage(codec, msg)
+	if err != nil {
+		return err
+	}
+
+	return writeMessage(w, messageData, payload)
+}
+
+func sendError(w io.Writer, err error) error {
+	return writeMessage(w, messageError, MarshalError(err))
+}
+
+func sendTrailer(w io.Writer, trailer Metadata) error {
+	payload, err := json.Marshal(trailer)
+	if err != nil {
+		return fmt.Errorf("encode trailer: %w", err)
+	}
+
+	return writeMessage(w, messageTrailer, payload)
+}
+
+// recvMessage reads the next message into dst. It returns [io.EOF] once the
+// stream is over and [*Error] if the other side sent an error. The trailer
+// received on the way is merged into trailer, if it is not nil.
+func recvMessage(r io.Reader, codec Codec, dst any, trailer *Metadata) error {
+	for {
+		var hdr [_messageHeaderSize]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return err
+		}
+
+		size := binary.BigEndian.Uint32(hdr[1:])
+		if size > _maxMessageSize {
+			return fmt.Errorf("message is too large: %d bytes", size)
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			if errors.Is(err, io.EOF) {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		switch messageKind(hdr[0]) {
+		case messageData:
+			if err := codec.Decode(bytes.NewReader(payload), dst); err != nil {
+				return fmt.Errorf("decode message: %w", err)
+			}
+			return nil
+		case messageError:
+			return UnmarshalError(payload)
+		case messageTrailer:
+			var md Metadata
+			if err := json.Unmarshal(payload, &md); err != nil {
+				return fmt.Errorf("decode trailer: %w", err)
+			}
+			if trailer != nil {
+				if *trailer == nil {
+					*trailer = Metadata{}
+				}
+				trailer.merge(md)
+			}
+			continue
+		}
+
+		return fmt.Errorf("unknown message kind: %d", hdr[0])
+	}
+}
@@ -0,0 +1,292 @@
+package srpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+)
+
+// CallServerStream calls the server-streaming serviceMethod on the remote
+// server and returns the iterator over the received messages. The call is
+// made once iteration starts. Iteration stops after the first error:
+//
+//	for item, err := range srpc.CallServerStream[Item](ctx, client, "Service.List", req) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Breaking the loop cancels the call.
+func CallServerStream[Resp any](ctx context.Context, c *Client, serviceMethod ServiceMethod, req any, opts ...CallOption) iter.Seq2[Resp, error] {
+	return func(yield func(Resp, error) bool) {
+		var zero Resp
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		reqBody, err := c.replayableBody(req)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		body, finish, err := c.start(ctx, serviceMethod, reqBody, opts)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		reuse := false
+		defer func() { finish(reuse) }()
+
+		trailer := newCallOptions(opts).trailer
+		for {
+			var msg Resp
+			err := recvMessage(body, c.codec, &msg, trailer)
+			if errors.Is(err, io.EOF) {
+				reuse = true
+				return
+			}
+
+			var rpcErr *Error
+			if errors.As(err, &rpcErr) {
+				reuse = drain(body)
+				yield(zero, fmt.Errorf("%w: %w", ErrServiceError, rpcErr))
+				return
+			}
+			if err != nil {
+				yield(zero, fmt.Errorf("%w: receive message: %w", ErrTransportError, err))
+				return
+			}
+
+			if !yield(msg, nil) {
+				return
+			}
+		}
+	}
+}
+
+// ClientStreamCall is the client side of the client-streaming call.
+type ClientStreamCall[Req, Resp any] struct {
+	sc *streamCall
+}
+
+// CallClientStream calls the client-streaming serviceMethod on the remote
+// server. Requests are sent with [ClientStreamCall.Send], the response is
+// received with [ClientStreamCall.CloseAndRecv]:
+//
+//	call := srpc.CallClientStream[Chunk, UploadResp](ctx, client, "Service.Upload")
+//	for _, chunk := range chunks {
+//		if err := call.Send(chunk); err != nil {
+//			break // the error is returned by CloseAndRecv
+//		}
+//	}
+//	resp, err := call.CloseAndRecv()
+//
+// Cancel ctx to abandon the call.
+func CallClientStream[Req, Resp any](ctx context.Context, c *Client, serviceMethod ServiceMethod, opts ...CallOption) *ClientStreamCall[Req, Resp] {
+	return &ClientStreamCall[Req, Resp]{sc: c.openStream(ctx, serviceMethod, opts)}
+}
+
+// Send sends msg to the server. It blocks until the transport takes it. If
+// the server has already finished the call, Send returns [io.EOF] and the
+// result is returned by [ClientStreamCall.CloseAndRecv].
+func (s *ClientStreamCall[Req, Resp]) Send(msg Req) error {
+	return s.sc.send(msg)
+}
+
+// CloseAndRecv closes the sending side of the stream and waits for the
+// response.
+func (s *ClientStreamCall[Req, Resp]) CloseAndRecv() (Resp, error) {
+	var resp Resp
+	s.sc.closeSend()
+
+	body, err := s.sc.response()
+	if err != nil {
+		return resp, err
+	}
+
+	if err := s.sc.codec.Decode(body, &resp); err != nil {
+		return resp, s.sc.end(fmt.Errorf("decode response body: %w", err), false)
+	}
+
+	return resp, s.sc.end(nil, drain(body))
+}
+
+// BidiStreamCall is the client side of the bidirectional streaming call.
+// Send and Recv can be called concurrently, but neither of them can be
+// called from several goroutines at once.
+type BidiStreamCall[Req, Resp any] struct {
+	sc *streamCall
+}
+
+// CallBidiStream calls the bidirectional streaming serviceMethod on the
+// remote server:
+//
+//	call := srpc.CallBidiStream[Message, Message](ctx, client, "Service.Chat")
+//	go func() {
+//		for _, msg := range outgoing {
+//			if err := call.Send(msg); err != nil {
+//				return
+//			}
+//		}
+//		call.CloseSend()
+//	}()
+//	for {
+//		msg, err := call.Recv()
+//		if errors.Is(err, io.EOF) {
+//			break
+//		}
+//		...
+//	}
+//
+// Cancel ctx to abandon the call.
+func CallBidiStream[Req, Resp any](ctx context.Context, c *Client, serviceMethod ServiceMethod, opts ...CallOption) *BidiStreamCall[Req, Resp] {
+	return &BidiStreamCall[Req, Resp]{sc: c.openStream(ctx, serviceMethod, opts)}
+}
+
+// Send sends msg to the server. It blocks until the transport takes it. If
+// the server has already finished the call, Send returns [io.EOF] and the
+// result is returned by [BidiStreamCall.Recv].
+func (s *BidiStreamCall[Req, Resp]) Send(msg Req) error {
+	return s.sc.send(msg)
+}
+
+// CloseSend closes the sending side of the stream, the server receives
+// [io.EOF]. Responses can still be received.
+func (s *BidiStreamCall[Req, Resp]) CloseSend() error {
+	return s.sc.closeSend()
+}
+
+// Recv receives the next message from the server. It returns [io.EOF] once
+// the server finishes the call successfully.
+func (s *BidiStreamCall[Req, Resp]) Recv() (Resp, error) {
+	var msg Resp
+	err := s.sc.recv(&msg)
+	return msg, err
+}
+
+// streamCall is the client side of the call with streamed request. The
+// request is sent in the background, so messages can be sent while the
+// response is being received.
+type streamCall struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	codec  Codec
+	reqW   *io.PipeWriter
+
+	trailer *Metadata // [Trailer] of the call, if any
+
+	ready      chan struct{} // closed once the response header is received
+	body       io.Reader
+	finish     func(reuse bool)
+	finishOnce sync.Once
+	err        error // error of the call, or [io.EOF] once it is over
+}
+
+func (c *Client) openStream(ctx context.Context, serviceMethod ServiceMethod, opts []CallOption) *streamCall {
+	ctx, cancel := context.WithCancel(ctx)
+	reqR, reqW := io.Pipe()
+
+	sc := &streamCall{
+		ctx:     ctx,
+		cancel:  cancel,
+		codec:   c.codec,
+		reqW:    reqW,
+		trailer: newCallOptions(opts).trailer,
+		ready:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(sc.ready)
+
+		body, finish, err := c.start(ctx, serviceMethod, requestBody{body: reqR}, opts)
+		if err != nil {
+			sc.err = err
+			cancel()
+			return
+		}
+
+		sc.body, sc.finish = body, finish
+		// the call can be abandoned at any moment, connection must be
+		// released anyway
+		context.AfterFunc(ctx, func() { sc.release(false) })
+	}()
+
+	return sc
+}
+
+func (sc *streamCall) send(msg any) error {
+	payload, err := encodeMessage(sc.codec, msg)
+	if err != nil {
+		return err
+	}
+
+	if err := writeMessage(sc.reqW, messageData, payload); err != nil {
+		// the call is over, its result is returned by receiving
+		return io.EOF
+	}
+
+	return nil
+}
+
+func (sc *streamCall) closeSend() error {
+	return sc.reqW.Close()
+}
+
+// response waits for the response header and returns the body.
+func (sc *streamCall) response() (io.Reader, error) {
+	<-sc.ready
+	if sc.err != nil {
+		return nil, sc.err
+	}
+
+	return sc.body, nil
+}
+
+func (sc *streamCall) recv(dst any) error {
+	body, err := sc.response()
+	if err != nil {
+		return err
+	}
+
+	err = recvMessage(body, sc.codec, dst, sc.trailer)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, io.EOF) {
+		return sc.end(io.EOF, true)
+	}
+
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return sc.end(fmt.Errorf("%w: %w", ErrServiceError, rpcErr), drain(body))
+	}
+
+	return sc.end(fmt.Errorf("%w: receive message: %w", ErrTransportError, err), false)
+}
+
+// end finishes the call with err, which is returned by the subsequent
+// receives. Nil err is stored as [io.EOF].
+func (sc *streamCall) end(err error, reuse bool) error {
+	sc.err = err
+	if sc.err == nil {
+		sc.err = io.EOF
+	}
+
+	sc.release(reuse)
+	sc.cancel()
+
+	return err
+}
+
+func (sc *streamCall) release(reuse bool) {
+	sc.finishOnce.Do(func() {
+		sc.reqW.CloseWithError(io.EOF)
+		sc.finish(reuse)
+	})
+}
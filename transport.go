@@ -10,12 +10,28 @@ type Connector interface {
 }
 
 type ClientConn interface {
+	// Do sends the request and returns once the response header is received.
+	// Request body can be streamed by the client, so Do must not wait until
+	// it is sent completely: the response body must be readable while the
+	// request body is still being sent.
 	Do(ctx context.Context, req Request) (Response, error)
 
 	// Close must be called after Send
 	Close() error
 }
 
+// ReusableConn is a [ClientConn], that can serve several sequential Do
+// calls. [Client] keeps such connections in the pool between the calls.
+// Do must release everything left from the previous call, e.g. the unread
+// response body.
+type ReusableConn interface {
+	ClientConn
+
+	// Healthy reports whether the connection can serve the next call.
+	// Unhealthy connections are closed.
+	Healthy() bool
+}
+
 var ErrListenerClosed = errors.New("listener is closed")
 
 type Listener interface {
@@ -26,6 +42,8 @@ type Listener interface {
 
 	// Close closes the listener.
 	// Any blocked Accept operations will be unblocked and return errors.
+	// Accepted connections must be served until they are closed, so the
+	// server can drain the calls in progress.
 	// Close can be called multiple times.
 	Close() error
 }
@@ -33,8 +51,16 @@ type Listener interface {
 type ServerConn interface {
 	Request() Request
 	Addr() string
+
+	// Context is cancelled when the client abandons the call or the
+	// connection is lost.
+	Context() context.Context
+
+	// Reply sends the response. Request body can still be read while the
+	// response body is being sent.
 	Reply(ctx context.Context, resp Response) error
 
-	// Close must be called after Send
+	// Close must be called after Send. The unread request body is dropped,
+	// so the client stops sending it.
 	Close() error
 }
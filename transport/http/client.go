@@ -2,7 +2,6 @@ package httptransport
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,7 +14,9 @@ func NewClientConnector(path string, method string) srpc.Connector {
 	return &Connector{
 		path:   path,
 		method: method,
-		client: &http.Client{},
+		// own transport, so idle connections are not shared with other
+		// connectors via [http.DefaultTransport]
+		client: &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
 	}
 }
 
@@ -46,10 +47,20 @@ type clientConn struct {
 }
 
 func (cl *clientConn) Do(ctx context.Context, req srpc.Request) (srpc.Response, error) {
+	// release the body of the previous call, if conn is reused
+	if err := cl.release(); err != nil {
+		return srpc.Response{}, fmt.Errorf("close previous response body: %w", err)
+	}
+
 	httpReq, err := http.NewRequestWithContext(ctx, cl.method, cl.url, req.Body)
 	if err != nil {
 		return srpc.Response{}, fmt.Errorf("create http request: %w", err)
 	}
+	// complete bodies of srpc know their length, so they are sent without
+	// chunked encoding
+	if b, ok := req.Body.(interface{ Len() int }); ok && b.Len() > 0 {
+		httpReq.ContentLength = int64(b.Len())
+	}
 
 	// WARN: is it safe to set a whole header like this?
 	httpReq.Header, err = toHeader(req.ServiceMethod, req.Metadata)
@@ -65,10 +76,12 @@ func (cl *clientConn) Do(ctx context.Context, req srpc.Request) (srpc.Response,
 	var resp srpc.Response
 
 	if httpResp.StatusCode != http.StatusOK {
+		defer httpResp.Body.Close()
 		respBody, err := io.ReadAll(httpResp.Body)
 		if err != nil {
 			return srpc.Response{}, fmt.Errorf("got bad status code: %s, cannot ready response body: %w", httpResp.Status, err)
 		}
+		resp.StatusCode = srpc.StatusInternalError
 		resp.Error = fmt.Errorf("got bad status code: %s, body: %s", httpResp.Status, respBody)
 		return resp, nil
 	}
@@ -93,7 +106,7 @@ func (cl *clientConn) Do(ctx context.Context, req srpc.Request) (srpc.Response,
 			return srpc.Response{}, fmt.Errorf("read error from response: %w", err)
 		}
 
-		resp.Error = errors.New(string(errMsg))
+		resp.Error = srpc.UnmarshalError(errMsg)
 	} else {
 		resp.Body = httpResp.Body
 	}
@@ -101,7 +114,23 @@ func (cl *clientConn) Do(ctx context.Context, req srpc.Request) (srpc.Response,
 	return resp, nil
 }
 
+// Healthy reports whether the conn can serve the next call. The underlying
+// connections are managed by [http.Client], so it is always true.
+func (cl *clientConn) Healthy() bool {
+	return true
+}
+
 // Close must be called after Send
 func (cl *clientConn) Close() error {
-	return cl.close()
+	return cl.release()
+}
+
+func (cl *clientConn) release() error {
+	if cl.close == nil {
+		return nil
+	}
+
+	close := cl.close
+	cl.close = nil
+	return close()
 }
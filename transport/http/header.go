@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/tymbaca/srpc"
 )
@@ -17,6 +19,10 @@ const (
 	_errorKey         = "Srpc-Error"
 )
 
+// _contentEncodings are the encodings of [srpc.EncodingKey], which are
+// standard HTTP content codings, so they are mapped to Content-Encoding.
+var _contentEncodings = []string{"gzip", "deflate", "br", "zstd"}
+
 func setStatus(h http.Header, status srpc.StatusCode) {
 	h.Set(_statusKey, strconv.Itoa(int(status)))
 }
@@ -53,10 +59,35 @@ func toHeader(serviceMethod srpc.ServiceMethod, metadata srpc.Metadata) (http.He
 	}
 
 	header.Set(_metadataKey, url.QueryEscape(string(mdJson)))
+	setEncoding(header, metadata)
 
 	return header, nil
 }
 
+// setEncoding maps the compression of the body to Content-Encoding and
+// Accept-Encoding, so it is visible to proxies.
+func setEncoding(h http.Header, md srpc.Metadata) {
+	if enc := md.Get(srpc.EncodingKey); len(enc) > 0 && slices.Contains(_contentEncodings, enc[0]) {
+		h.Set("Content-Encoding", enc[0])
+	}
+
+	if accept := md.Get(srpc.AcceptEncodingKey); len(accept) > 0 {
+		var std []string
+		for _, enc := range accept {
+			if slices.Contains(_contentEncodings, enc) {
+				std = append(std, enc)
+			}
+		}
+
+		// set in any case, otherwise [http.Transport] asks for gzip and
+		// decompresses the response itself
+		if len(std) == 0 {
+			std = []string{"identity"}
+		}
+		h.Set("Accept-Encoding", strings.Join(std, ", "))
+	}
+}
+
 func fromHeader(header http.Header) (srpc.ServiceMethod, srpc.Metadata, error) {
 	serviceMethod := srpc.ServiceMethod(header.Get(_serviceMethodKey))
 
@@ -71,5 +102,10 @@ func fromHeader(header http.Header) (srpc.ServiceMethod, srpc.Metadata, error) {
 		return "", nil, fmt.Errorf("unmarshal metadata json: %w", err)
 	}
 
+	// e.g. the body is compressed by a proxy
+	if enc := header.Get("Content-Encoding"); enc != "" && enc != "identity" && len(md.Get(srpc.EncodingKey)) == 0 {
+		md.Set(srpc.EncodingKey, enc)
+	}
+
 	return serviceMethod, md, nil
 }
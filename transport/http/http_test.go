@@ -1,14 +1,19 @@
 package httptransport
 
 import (
+	"io"
 	"math/rand/v2"
 	"net/http"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"github.com/tymbaca/srpc"
 	"github.com/tymbaca/srpc/codec"
+	_ "github.com/tymbaca/srpc/compress"
+	_ "github.com/tymbaca/srpc/compress/snappy"
+	"github.com/tymbaca/srpc/internal/srpctest"
 	"github.com/tymbaca/srpc/logger"
 	"github.com/tymbaca/srpc/transport/testdata"
 	"go.uber.org/goleak"
@@ -19,6 +24,8 @@ func TestMain(m *testing.M) {
 		goleak.IgnoreAnyFunction("net.(*sysDialer).dialParallel"),
 		goleak.IgnoreAnyFunction("net.(*sysDialer).dialParallel.func1"),
 		goleak.IgnoreAnyFunction("net.(*netFD).connect.func2"),
+		// connection with unread request body is closed with a delay
+		goleak.IgnoreAnyFunction("net/http.(*conn).closeWriteAndWait"),
 	)
 }
 
@@ -42,7 +49,79 @@ func TestHttpTransport(t *testing.T) {
 	}
 	{
 		_, err := client.Divide(ctx, testdata.DivideReq{A: 10, B: 0})
-		require.Error(t, err)
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(err))
+		detail, ok := srpc.ErrorDetail[testdata.DivideByZero](err)
+		require.True(t, ok)
+		require.Equal(t, 10, detail.A)
+	}
+	{
+		var got []int
+		for resp, err := range client.Count(ctx, testdata.CountReq{N: 5}) {
+			require.NoError(t, err)
+			got = append(got, resp.I)
+		}
+		require.Equal(t, []int{0, 1, 2, 3, 4}, got)
+	}
+	{
+		// breaking the loop abandons the stream
+		for resp, err := range client.Count(ctx, testdata.CountReq{N: 1000}) {
+			require.NoError(t, err)
+			if resp.I == 2 {
+				break
+			}
+		}
+	}
+	{
+		var errs []error
+		for _, err := range client.Count(ctx, testdata.CountReq{N: -1}) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		require.ErrorIs(t, errs[0], srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(errs[0]))
+	}
+	{
+		call := client.Sum(ctx)
+		for i := range 5 {
+			require.NoError(t, call.Send(testdata.SumReq{N: i}))
+		}
+		resp, err := call.CloseAndRecv()
+		require.NoError(t, err)
+		require.Equal(t, 10, resp.Sum)
+	}
+	{
+		call := client.Sum(ctx)
+		require.NoError(t, call.Send(testdata.SumReq{N: -1}))
+		_, err := call.CloseAndRecv()
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(err))
+	}
+	{
+		call := client.Echo(ctx)
+		for _, text := range []string{"a", "b", "c"} {
+			require.NoError(t, call.Send(testdata.EchoMsg{Text: text}))
+			msg, err := call.Recv()
+			require.NoError(t, err)
+			require.Equal(t, text, msg.Text)
+		}
+		require.NoError(t, call.CloseSend())
+		_, err := call.Recv()
+		require.ErrorIs(t, err, io.EOF)
+	}
+	{
+		// server finishes the call before the client
+		call := client.Echo(ctx)
+		require.NoError(t, call.Send(testdata.EchoMsg{Text: "stop"}))
+		_, err := call.Recv()
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeAborted, srpc.CodeOf(err))
+		require.ErrorIs(t, call.Send(testdata.EchoMsg{Text: "a"}), io.EOF)
+	}
+	{
+		err := client.Call(ctx, "TestService.Unknown", testdata.AddReq{}, &testdata.AddResp{})
+		require.ErrorIs(t, err, srpc.ErrTransportError)
+		require.Equal(t, srpc.CodeUnimplemented, srpc.CodeOf(err))
 	}
 }
 
@@ -83,6 +162,79 @@ func TestHttpTransportStress(t *testing.T) {
 	})
 }
 
+func TestHttpCompression(t *testing.T) {
+	ctx := t.Context()
+
+	server := testdata.NewTestServiceServer(srpc.NewServer(codec.JSON, srpc.WithServerCompression(srpc.CompressionConfig{MinSize: 1})))
+	defer server.Close()
+	go server.Start(ctx, CreateAndStartListener(":8080", "/srpc", http.MethodPost))
+
+	for _, name := range []string{"gzip", "snappy"} {
+		client := testdata.NewTestServiceClient(srpc.NewClient("http://localhost:8080", codec.JSON, NewClientConnector("/srpc", http.MethodPost),
+			srpc.WithClientCompression(srpc.CompressionConfig{Compressor: name, MinSize: 1}),
+		))
+
+		var header srpc.Metadata
+		resp, err := client.Add(ctx, testdata.AddReq{A: 10, B: 15}, srpc.Header(&header))
+		require.NoError(t, err)
+		require.Equal(t, 25, resp.Result)
+		require.Equal(t, []string{name}, header.Get(srpc.EncodingKey))
+
+		var got []int
+		for resp, err := range client.Count(ctx, testdata.CountReq{N: 3}) {
+			require.NoError(t, err)
+			got = append(got, resp.I)
+		}
+		require.Equal(t, []int{0, 1, 2}, got)
+	}
+
+	// standard encodings are mapped to Content-Encoding
+	h, err := toHeader("TestService.Add", srpc.Metadata{
+		srpc.EncodingKey:       {"gzip"},
+		srpc.AcceptEncodingKey: {"gzip", "snappy", "zstd"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "gzip", h.Get("Content-Encoding"))
+	require.Equal(t, "gzip, zstd", h.Get("Accept-Encoding"))
+
+	h, err = toHeader("TestService.Add", srpc.Metadata{
+		srpc.EncodingKey:       {"snappy"},
+		srpc.AcceptEncodingKey: {"snappy"},
+	})
+	require.NoError(t, err)
+	require.Empty(t, h.Get("Content-Encoding"))
+	require.Equal(t, "identity", h.Get("Accept-Encoding"))
+
+	h, err = toHeader("TestService.Add", srpc.Metadata{})
+	require.NoError(t, err)
+	h.Set("Content-Encoding", "gzip")
+	_, md, err := fromHeader(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"gzip"}, md.Get(srpc.EncodingKey))
+
+	// encodings without the compressor are mapped too, so the call fails
+	// instead of decoding the compressed body
+	h, err = toHeader("TestService.Add", srpc.Metadata{})
+	require.NoError(t, err)
+	h.Set("Content-Encoding", "br")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost:8080/srpc", strings.NewReader("compressed"))
+	require.NoError(t, err)
+	req.Header = h
+
+	httpResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer httpResp.Body.Close()
+
+	status, err := getStatus(httpResp.Header)
+	require.NoError(t, err)
+	require.Equal(t, srpc.StatusBadRequest, status)
+
+	body, err := io.ReadAll(httpResp.Body)
+	require.NoError(t, err)
+	require.ErrorContains(t, srpc.UnmarshalError(body), `unsupported encoding "br"`)
+}
+
 func BenchmarkHttpTransportStress(b *testing.B) {
 	ctx := b.Context()
 
@@ -92,9 +244,31 @@ func BenchmarkHttpTransportStress(b *testing.B) {
 
 	client := testdata.NewTestServiceClient(srpc.NewClient("http://localhost:8080", codec.JSON, NewClientConnector("/srpc", http.MethodPost)))
 
+	b.ReportAllocs()
 	for b.Loop() {
 		req := testdata.AddReq{A: rand.Int(), B: rand.Int()}
 		resp, err := client.Add(ctx, req)
 		_, _ = resp, err
 	}
 }
+
+func BenchmarkHttpTransportCodecs(b *testing.B) {
+	for _, bc := range srpctest.Codecs {
+		b.Run(bc.Name, func(b *testing.B) {
+			ctx := b.Context()
+
+			server := testdata.NewTestServiceServer(srpc.NewServer(bc.Codec, srpc.WithLogger(logger.DefaulSLogger{})))
+			go server.Start(ctx, CreateAndStartListener(":8080", "/srpc", http.MethodPost))
+			defer server.Close()
+
+			client := testdata.NewTestServiceClient(srpc.NewClient("http://localhost:8080", bc.Codec, NewClientConnector("/srpc", http.MethodPost)))
+
+			b.ReportAllocs()
+			for b.Loop() {
+				req := testdata.AddReq{A: rand.Int(), B: rand.Int()}
+				resp, err := client.Add(ctx, req)
+				_, _ = resp, err
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package httptransport
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"sync"
 
@@ -20,9 +22,21 @@ type Listener struct {
 	conns     chan srpc.ServerConn
 }
 
+// CreateAndStartListener creates the listener and starts serving in the
+// background. The address is bound before return, so clients can connect
+// right away. If binding fails, the error is logged and the returned listener
+// is closed.
 func CreateAndStartListener(addr string, path string, method string) *Listener {
 	l := NewServerListener(addr, path, method)
-	go l.Start()
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("http listener: listen %s: %v", addr, err)
+		l.Close()
+		return l
+	}
+
+	go l.serve(ln)
 	return l
 }
 
@@ -43,13 +57,25 @@ func NewServerListener(addr string, path string, method string) *Listener {
 }
 
 func (l *Listener) Start() error {
+	ln, err := net.Listen("tcp", l.server.Addr)
+	if err != nil {
+		l.Close()
+		return err
+	}
+
+	return l.serve(ln)
+}
+
+func (l *Listener) serve(ln net.Listener) error {
 	defer l.Close()
 
-	return l.server.ListenAndServe()
+	return l.server.Serve(ln)
 }
 
 // Close closes the listener.
 // Any blocked Accept operations will be unblocked and return errors.
+// Accepted calls are served until they are closed, idle connections are
+// closed right away, the others once their calls are finished.
 // Close can be called multiple times.
 func (l *Listener) Close() (err error) {
 	l.closeOnce.Do(func() { err = l.close() })
@@ -58,7 +84,16 @@ func (l *Listener) Close() (err error) {
 
 func (l *Listener) close() error {
 	l.ctxCancel()
-	return l.server.Close()
+
+	// with cancelled context Shutdown doesn't wait for active connections,
+	// they are closed by [http.Server] once the calls are finished
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.server.Shutdown(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return nil
 }
 
 // Accept waits and returns new connection to the listener.
@@ -77,11 +112,13 @@ func (l *Listener) Accept() (srpc.ServerConn, error) {
 }
 
 func (l *Listener) handler(w http.ResponseWriter, r *http.Request) {
-	defer func() {
-		if r.Body != nil {
-			r.Body.Close()
-		}
-	}()
+	// r.Body is not closed here: closing waits for the rest of the body,
+	// while the client of a streaming call waits for the end of the response.
+	// [http.Server] closes it once the response is finished.
+
+	// request body of streaming calls is read while the response is written
+	http.NewResponseController(w).EnableFullDuplex()
+
 	serviceMethod, metadata, err := fromHeader(r.Header)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -105,13 +142,13 @@ func (l *Listener) handler(w http.ResponseWriter, r *http.Request) {
 	select {
 	case l.conns <- conn:
 	case <-l.ctx.Done():
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("server is shutting down"))
+		return
 	}
 
 	// wait until srpc handles the connection and calls [serverConn.Close]
-	select {
-	case <-conn.closeHandlerCh:
-	case <-l.ctx.Done():
-	}
+	<-conn.closeHandlerCh
 }
 
 type serverConn struct {
@@ -131,6 +168,10 @@ func (c *serverConn) Addr() string {
 	return c.r.RemoteAddr
 }
 
+func (c *serverConn) Context() context.Context {
+	return c.r.Context()
+}
+
 func (c *serverConn) Reply(ctx context.Context, resp srpc.Response) error {
 	header, err := toHeader(resp.ServiceMethod, resp.Metadata)
 	if err != nil {
@@ -146,11 +187,12 @@ func (c *serverConn) Reply(ctx context.Context, resp srpc.Response) error {
 	setStatus(c.w.Header(), resp.StatusCode)
 	if resp.Error != nil {
 		setError(c.w.Header())
-		c.w.Write([]byte(resp.Error.Error()))
+		c.w.Write(srpc.MarshalError(resp.Error))
 		return nil
 	}
 
-	n, err := io.Copy(c.w, resp.Body)
+	// flush every write, so streamed messages reach the client immediately
+	n, err := io.Copy(flushWriter{c.w}, resp.Body)
 	if err != nil {
 		return fmt.Errorf("failed to send body (%d bytes written): %w", n, err)
 	}
@@ -163,3 +205,16 @@ func (c *serverConn) Close() error {
 	close(c.closeHandlerCh)
 	return nil
 }
+
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	return n, http.NewResponseController(fw.w).Flush()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"math"
@@ -32,7 +33,7 @@ func (c *Cluster) NewPeer() *Peer {
 	addr := c.nextAddr()
 	peer := &Peer{
 		cluster: c, addr: addr,
-		inbox: make(chan *conn),
+		inbox: make(chan *call),
 	}
 
 	c.peers[addr] = peer
@@ -59,7 +60,10 @@ func (c *Cluster) nextAddr() string {
 type Peer struct {
 	cluster *Cluster
 	addr    string
-	inbox   chan *conn // only for delegating to peerListener
+	inbox   chan *call // only for delegating to peerListener
+
+	mu       sync.Mutex
+	listener *peerListener // the last one
 }
 
 func (p *Peer) Listen() *peerListener {
@@ -68,15 +72,33 @@ func (p *Peer) Listen() *peerListener {
 		inbox:  p.inbox,
 	}
 	l.ctx, l.cancel = context.WithCancel(context.Background())
+
+	p.mu.Lock()
+	p.listener = l
+	p.mu.Unlock()
+
 	return l
 }
 
+// closed returns the channel, which is closed once the peer stops
+// listening. It is nil if the peer hasn't started listening yet, so calls
+// wait for it.
+func (p *Peer) closed() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.listener == nil {
+		return nil
+	}
+	return p.listener.ctx.Done()
+}
+
 type peerListener struct {
 	parent *Peer // for debug purposes
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	inbox chan *conn
+	inbox chan *call
 }
 
 // Accept waits and returns new connection to the listener.
@@ -101,7 +123,12 @@ func (pl *peerListener) Close() error {
 	return nil
 }
 
-var ErrPeerNotFound = errors.New("peer not found")
+var (
+	ErrPeerNotFound     = errors.New("peer not found")
+	ErrPeerNotListening = errors.New("peer is not listening")
+
+	errCallClosed = errors.New("call is closed by the server")
+)
 
 func (p *Peer) Connect(_ context.Context, addr string) (srpc.ClientConn, error) {
 	target := p.cluster.getPeer(addr)
@@ -118,46 +145,93 @@ func (p *Peer) Addr() string {
 	return p.addr
 }
 
+// conn is the client side of the connection. It sends every call to the
+// server as a separate [call], so it can be reused.
 type conn struct {
 	client, server *Peer
 
-	ctx     context.Context
-	cancel  context.CancelFunc
-	req     srpc.Request
-	replyCh chan srpc.Response
+	last *call // the response body of the last call may still be read
 }
 
 func (c *conn) Do(ctx context.Context, req srpc.Request) (srpc.Response, error) {
-	c.req = req
-	c.replyCh = make(chan srpc.Response)
-	c.ctx, c.cancel = context.WithCancel(ctx)
-	defer c.cancel()
+	c.release()
+
+	cl := &call{
+		client:  c.client,
+		req:     req,
+		replyCh: make(chan srpc.Response),
+	}
+	cl.ctx, cl.cancel = context.WithCancel(ctx)
 
 	debug("wait send conn to target inbox, me: %+v, target: %+v", c.client, c.server)
 
 	select {
-	case <-c.ctx.Done():
+	case <-cl.ctx.Done():
+		cl.cancel()
 		return srpc.Response{}, ctx.Err()
-	case c.server.inbox <- c:
+	case <-c.server.closed():
+		cl.cancel()
+		return srpc.Response{}, ErrPeerNotListening
+	case c.server.inbox <- cl:
 	}
 
 	select {
-	case <-c.ctx.Done():
-		return srpc.Response{}, ctx.Err()
-	case resp := <-c.replyCh:
+	case <-cl.ctx.Done():
+		cl.cancel()
+		if ctx.Err() != nil {
+			return srpc.Response{}, ctx.Err()
+		}
+		return srpc.Response{}, errCallClosed
+	case resp := <-cl.replyCh:
+		// the server is being waited until the body is read, so the call
+		// is cancelled only with the next call or [conn.Close]
+		c.last = cl
 		return resp, nil
 	}
 }
 
-func (c *conn) Request() srpc.Request {
+// release cancels the last call.
+func (c *conn) release() {
+	if c.last != nil {
+		c.last.cancel()
+		c.last = nil
+	}
+}
+
+// Healthy reports whether the conn can serve the next call.
+func (c *conn) Healthy() bool {
+	return c.client.cluster.getPeer(c.server.addr) != nil
+}
+
+// Close must be called after Send
+func (c *conn) Close() error {
+	c.release()
+	return nil
+}
+
+// call is the server side of the connection.
+type call struct {
+	client *Peer
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	req     srpc.Request
+	replyCh chan srpc.Response
+}
+
+func (c *call) Request() srpc.Request {
 	return c.req
 }
 
-func (c *conn) Addr() string {
+func (c *call) Addr() string {
 	return c.client.Addr()
 }
 
-func (c *conn) Reply(ctx context.Context, resp srpc.Response) error {
+func (c *call) Context() context.Context {
+	return c.ctx
+}
+
+func (c *call) Reply(ctx context.Context, resp srpc.Response) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -167,11 +241,12 @@ func (c *conn) Reply(ctx context.Context, resp srpc.Response) error {
 }
 
 // Close must be called after Send
-func (c *conn) Close() error {
-	if c.cancel != nil {
-		c.cancel()
+func (c *call) Close() error {
+	c.cancel()
+	// the request body is read directly from the client, stop it sending
+	if body, ok := c.req.Body.(io.Closer); ok {
+		body.Close()
 	}
-
 	return nil
 }
 
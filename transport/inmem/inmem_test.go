@@ -1,6 +1,7 @@
 package inmem
 
 import (
+	"io"
 	"math/rand/v2"
 	"sync"
 	"testing"
@@ -42,7 +43,79 @@ func TestInmemTransport(t *testing.T) {
 	}
 	{
 		_, err := client.Divide(ctx, testdata.DivideReq{A: 10, B: 0})
-		require.Error(t, err)
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(err))
+		detail, ok := srpc.ErrorDetail[testdata.DivideByZero](err)
+		require.True(t, ok)
+		require.Equal(t, 10, detail.A)
+	}
+	{
+		var got []int
+		for resp, err := range client.Count(ctx, testdata.CountReq{N: 5}) {
+			require.NoError(t, err)
+			got = append(got, resp.I)
+		}
+		require.Equal(t, []int{0, 1, 2, 3, 4}, got)
+	}
+	{
+		// breaking the loop abandons the stream
+		for resp, err := range client.Count(ctx, testdata.CountReq{N: 1000}) {
+			require.NoError(t, err)
+			if resp.I == 2 {
+				break
+			}
+		}
+	}
+	{
+		var errs []error
+		for _, err := range client.Count(ctx, testdata.CountReq{N: -1}) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		require.ErrorIs(t, errs[0], srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(errs[0]))
+	}
+	{
+		call := client.Sum(ctx)
+		for i := range 5 {
+			require.NoError(t, call.Send(testdata.SumReq{N: i}))
+		}
+		resp, err := call.CloseAndRecv()
+		require.NoError(t, err)
+		require.Equal(t, 10, resp.Sum)
+	}
+	{
+		call := client.Sum(ctx)
+		require.NoError(t, call.Send(testdata.SumReq{N: -1}))
+		_, err := call.CloseAndRecv()
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(err))
+	}
+	{
+		call := client.Echo(ctx)
+		for _, text := range []string{"a", "b", "c"} {
+			require.NoError(t, call.Send(testdata.EchoMsg{Text: text}))
+			msg, err := call.Recv()
+			require.NoError(t, err)
+			require.Equal(t, text, msg.Text)
+		}
+		require.NoError(t, call.CloseSend())
+		_, err := call.Recv()
+		require.ErrorIs(t, err, io.EOF)
+	}
+	{
+		// server finishes the call before the client
+		call := client.Echo(ctx)
+		require.NoError(t, call.Send(testdata.EchoMsg{Text: "stop"}))
+		_, err := call.Recv()
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeAborted, srpc.CodeOf(err))
+		require.ErrorIs(t, call.Send(testdata.EchoMsg{Text: "a"}), io.EOF)
+	}
+	{
+		err := client.Call(ctx, "TestService.Unknown", testdata.AddReq{}, &testdata.AddResp{})
+		require.ErrorIs(t, err, srpc.ErrTransportError)
+		require.Equal(t, srpc.CodeUnimplemented, srpc.CodeOf(err))
 	}
 }
 
@@ -0,0 +1,361 @@
+package tcptransport
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/tymbaca/srpc"
+)
+
+// NewClientConnector returns the connector, which dials a new connection
+// for every Connect.
+func NewClientConnector() *Connector {
+	return &Connector{
+		dialer: &net.Dialer{},
+	}
+}
+
+// NewMuxConnector returns the connector, which multiplexes all calls to the
+// same address over a single long-lived connection. Every call gets its own
+// stream, so responses can come out of order and a call can be cancelled
+// without tearing down the connection. If the connection is lost, the next
+// Connect dials a new one.
+//
+// Call [Connector.Close] to close the shared connections.
+func NewMuxConnector() *Connector {
+	return &Connector{
+		dialer:   &net.Dialer{},
+		mux:      true,
+		sessions: make(map[string]*clientSession),
+	}
+}
+
+type Connector struct {
+	dialer *net.Dialer
+
+	mux      bool
+	mu       sync.Mutex
+	sessions map[string]*clientSession
+}
+
+func (cl *Connector) Connect(ctx context.Context, addr string) (srpc.ClientConn, error) {
+	if !cl.mux {
+		sess, err := dialSession(ctx, cl.dialer, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &clientConn{sess: sess, ownsSession: true}, nil
+	}
+
+	sess, err := cl.session(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clientConn{sess: sess}, nil
+}
+
+// session returns the shared session for addr, dialing a new one if there is
+// none or it is broken.
+func (cl *Connector) session(ctx context.Context, addr string) (*clientSession, error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if sess, ok := cl.sessions[addr]; ok && sess.alive() {
+		return sess, nil
+	}
+
+	sess, err := dialSession(ctx, cl.dialer, addr)
+	if err != nil {
+		return nil, err
+	}
+	cl.sessions[addr] = sess
+
+	return sess, nil
+}
+
+// Close closes the shared connections of the mux connector. Calls in
+// progress fail.
+func (cl *Connector) Close() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	var errs []error
+	for addr, sess := range cl.sessions {
+		errs = append(errs, sess.close())
+		delete(cl.sessions, addr)
+	}
+
+	return errors.Join(errs...)
+}
+
+// clientSession is a connection with the streams of the calls, which are
+// in progress.
+type clientSession struct {
+	nc net.Conn
+	fw *frameWriter
+
+	mu      sync.Mutex
+	streams map[uint32]*clientStream
+	lastID  uint32
+	err     error         // set once the connection is lost
+	done    chan struct{} // closed once the connection is lost
+}
+
+type clientStream struct {
+	id      uint32
+	header  chan header // receives response header
+	body    *buffer
+	sendWin *window
+}
+
+var errSessionClosed = errors.New("connection is closed")
+
+func dialSession(ctx context.Context, dialer *net.Dialer, addr string) (*clientSession, error) {
+	nc, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial tcp: %w", err)
+	}
+
+	sess := &clientSession{
+		nc:      nc,
+		fw:      newFrameWriter(nc),
+		streams: make(map[uint32]*clientStream),
+		done:    make(chan struct{}),
+	}
+	go sess.readLoop()
+
+	return sess, nil
+}
+
+func (s *clientSession) readLoop() {
+	r := bufio.NewReader(s.nc)
+	for {
+		typ, id, payload, err := readFrame(r)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+
+		st := s.stream(id)
+		if st == nil {
+			continue // abandoned stream
+		}
+
+		switch typ {
+		case frameResponse:
+			h, err := unmarshalHeader(payload)
+			if err != nil {
+				s.fail(err)
+				return
+			}
+			select {
+			case st.header <- h:
+			default: // duplicate header, ignore
+			}
+		case frameData:
+			if err := st.body.write(payload); err != nil {
+				s.fail(err)
+				return
+			}
+		case frameEnd:
+			st.body.closeWithError(endError(payload))
+			// server has finished the call, it won't read the request
+			st.sendWin.close(errStreamClosed)
+			s.remove(id)
+		case frameWindow:
+			n, err := parseWindow(payload)
+			if err != nil {
+				s.fail(err)
+				return
+			}
+			st.sendWin.add(n)
+		default:
+			s.fail(fmt.Errorf("unexpected frame type %d", typ))
+			return
+		}
+	}
+}
+
+func (s *clientSession) newStream() (*clientStream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	s.lastID++
+	id := s.lastID
+	st := &clientStream{
+		id:     id,
+		header: make(chan header, 1),
+		body: newBuffer(func(n int) {
+			s.fw.writeFrame(frameWindow, id, windowPayload(n))
+		}),
+		sendWin: newWindow(),
+	}
+	s.streams[st.id] = st
+
+	return st, nil
+}
+
+func (s *clientSession) stream(id uint32) *clientStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.streams[id]
+}
+
+// remove forgets the stream, reporting whether it was still in progress.
+func (s *clientSession) remove(id uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.streams[id]
+	delete(s.streams, id)
+	return ok
+}
+
+func (s *clientSession) alive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err == nil
+}
+
+// fail closes the connection and fails all streams in progress.
+func (s *clientSession) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+
+	s.err = unexpectedEOF(err)
+	for id, st := range s.streams {
+		st.body.abort(s.err)
+		st.sendWin.close(s.err)
+		delete(s.streams, id)
+	}
+	close(s.done)
+	s.nc.Close()
+}
+
+func (s *clientSession) close() error {
+	s.fail(errSessionClosed)
+	return nil
+}
+
+type clientConn struct {
+	sess        *clientSession
+	ownsSession bool // session is closed along with the conn
+
+	st   *clientStream
+	stop func() bool // stops cancelling the stream on context cancellation
+}
+
+func (cl *clientConn) Do(ctx context.Context, req srpc.Request) (resp srpc.Response, err error) {
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+	}()
+
+	// release the stream of the previous call, if conn is reused
+	cl.release()
+
+	cl.st, err = cl.sess.newStream()
+	if err != nil {
+		return srpc.Response{}, fmt.Errorf("open stream: %w", err)
+	}
+
+	// the response body is read after Do returns, so the stream is being
+	// cancelled on context cancellation until [clientConn.Close]
+	st := cl.st
+	cl.stop = context.AfterFunc(ctx, func() { cl.cancel(st, ctx.Err()) })
+
+	err = cl.sess.fw.writeFrame(frameRequest, cl.st.id, marshalHeader(header{
+		ServiceMethod: req.ServiceMethod,
+		Metadata:      req.Metadata,
+	}))
+	if err != nil {
+		return srpc.Response{}, fmt.Errorf("write request header: %w", err)
+	}
+
+	// request body may be streamed, so it is sent along with receiving the
+	// response
+	go func() {
+		if err := cl.sess.fw.writeBody(st.id, req.Body, st.sendWin); err != nil {
+			// the stream is over, stop the sender
+			if body, ok := req.Body.(io.Closer); ok {
+				body.Close()
+			}
+		}
+	}()
+
+	var h header
+	select {
+	case h = <-cl.st.header:
+	case <-cl.sess.done:
+		return srpc.Response{}, fmt.Errorf("read response header: %w", cl.sess.err)
+	case <-ctx.Done():
+		return srpc.Response{}, ctx.Err()
+	}
+
+	resp = srpc.Response{
+		ServiceMethod: h.ServiceMethod,
+		Metadata:      h.Metadata,
+		StatusCode:    h.StatusCode,
+	}
+
+	if h.HasError {
+		resp.Error = srpc.UnmarshalError([]byte(h.Error))
+	} else {
+		resp.Body = cl.st.body
+	}
+
+	return resp, nil
+}
+
+// release abandons the stream of the last call.
+func (cl *clientConn) release() {
+	if cl.stop != nil {
+		cl.stop()
+		cl.stop = nil
+	}
+	if cl.st != nil {
+		cl.cancel(cl.st, errStreamClosed)
+		cl.st = nil
+	}
+}
+
+// cancel abandons the stream, if it is still in progress.
+func (cl *clientConn) cancel(st *clientStream, err error) {
+	st.body.abort(err)
+	st.sendWin.close(err)
+	if cl.sess.remove(st.id) {
+		cl.sess.fw.writeFrame(frameCancel, st.id, nil)
+	}
+}
+
+// Healthy reports whether the conn can serve the next call.
+func (cl *clientConn) Healthy() bool {
+	return cl.sess.alive()
+}
+
+// Close must be called after Send
+func (cl *clientConn) Close() error {
+	cl.release()
+	if cl.ownsSession {
+		return cl.sess.close()
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package tcptransport
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tymbaca/srpc"
+)
+
+// Every message on the wire is a frame:
+//
+//	| type (1 byte) | stream ID (4 bytes) | payload length (4 bytes) | payload |
+//
+// Numbers are big endian. A call is a stream of frames with the same ID,
+// chosen by the client. Frames of different streams can interleave, so
+// several calls can share one connection.
+//
+// Each side of the stream sends a header frame ([frameRequest] or
+// [frameResponse]), followed by any amount of [frameData] frames with the
+// body and a [frameEnd] frame. Payload of [frameEnd] is the error text, if the
+// body could not be sent completely. Client can abandon the call by sending
+// [frameCancel].
+//
+// Bodies of both sides can be sent at the same time, which makes
+// bidirectional streams possible. They are flow controlled: each side can
+// send at most [_initialWindow] bytes of [frameData] payload per stream,
+// which the other side hasn't read yet. Once the body is read, the reader
+// grants more with [frameWindow], its payload is a 4 byte increment.
+type frameType byte
+
+const (
+	frameRequest frameType = iota + 1
+	frameResponse
+	frameData
+	frameEnd
+	frameCancel
+	frameWindow
+)
+
+const (
+	_frameHeaderSize = 9
+	_maxFrameSize    = 16 << 20
+	_dataChunkSize   = 32 << 10
+	_initialWindow   = 256 << 10
+)
+
+var errFrameTooLarge = errors.New("frame is too large")
+
+func writeFrame(w io.Writer, typ frameType, id uint32, payload []byte) error {
+	if len(payload) > _maxFrameSize {
+		return errFrameTooLarge
+	}
+
+	var hdr [_frameHeaderSize]byte
+	hdr[0] = byte(typ)
+	binary.BigEndian.PutUint32(hdr[1:], id)
+	binary.BigEndian.PutUint32(hdr[5:], uint32(len(payload)))
+
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+func readFrame(r io.Reader) (typ frameType, id uint32, payload []byte, err error) {
+	var hdr [_frameHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, 0, nil, err
+	}
+
+	size := binary.BigEndian.Uint32(hdr[5:])
+	if size > _maxFrameSize {
+		return 0, 0, nil, errFrameTooLarge
+	}
+
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, 0, nil, unexpectedEOF(err)
+	}
+
+	return frameType(hdr[0]), binary.BigEndian.Uint32(hdr[1:]), payload, nil
+}
+
+func windowPayload(n int) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(n))
+}
+
+func parseWindow(payload []byte) (int, error) {
+	if len(payload) != 4 {
+		return 0, fmt.Errorf("invalid window update size: %d", len(payload))
+	}
+
+	return int(binary.BigEndian.Uint32(payload)), nil
+}
+
+// header is the payload of [frameRequest] and [frameResponse] frames.
+// StatusCode and Error are only meaningful for responses. Error is encoded
+// with [srpc.MarshalError].
+type header struct {
+	ServiceMethod srpc.ServiceMethod
+	Metadata      srpc.Metadata
+	StatusCode    srpc.StatusCode
+	HasError      bool
+	Error         string
+}
+
+func marshalHeader(h header) []byte {
+	b := appendString(nil, string(h.ServiceMethod))
+
+	b = binary.AppendUvarint(b, uint64(len(h.Metadata)))
+	for k, vs := range h.Metadata {
+		b = appendString(b, k)
+		b = binary.AppendUvarint(b, uint64(len(vs)))
+		for _, v := range vs {
+			b = appendString(b, v)
+		}
+	}
+
+	b = binary.AppendVarint(b, int64(h.StatusCode))
+	if h.HasError {
+		b = append(b, 1)
+		b = appendString(b, h.Error)
+	} else {
+		b = append(b, 0)
+	}
+
+	return b
+}
+
+func unmarshalHeader(b []byte) (h header, err error) {
+	d := decoder{b: b}
+
+	h.ServiceMethod = srpc.ServiceMethod(d.string())
+
+	mdLen := d.uvarint()
+	h.Metadata = make(srpc.Metadata, min(mdLen, uint64(len(b))))
+	for range mdLen {
+		if d.err != nil {
+			break
+		}
+		k := d.string()
+		vsLen := d.uvarint()
+		vs := make([]string, 0, min(vsLen, uint64(len(b))))
+		for range vsLen {
+			if d.err != nil {
+				break
+			}
+			vs = append(vs, d.string())
+		}
+		h.Metadata[k] = vs
+	}
+
+	h.StatusCode = srpc.StatusCode(d.varint())
+	h.HasError = d.byte() == 1
+	if h.HasError {
+		h.Error = d.string()
+	}
+
+	if d.err != nil {
+		return header{}, fmt.Errorf("decode header: %w", d.err)
+	}
+
+	return h, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// decoder reads values from b, remembering the first error.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.b) == 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := d.b[0]
+	d.b = d.b[1:]
+	return v
+}
+
+func (d *decoder) string() string {
+	size := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if size > uint64(len(d.b)) {
+		d.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	v := string(d.b[:size])
+	d.b = d.b[size:]
+	return v
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
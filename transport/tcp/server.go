@@ -0,0 +1,365 @@
+package tcptransport
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/tymbaca/srpc"
+)
+
+type Listener struct {
+	ln net.Listener
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+	closeOnce sync.Once
+	conns     chan srpc.ServerConn
+
+	mu     sync.Mutex
+	active map[*serverSession]struct{}
+	wg     sync.WaitGroup
+}
+
+// Listen binds the address and starts accepting connections in the
+// background.
+func Listen(addr string) (*Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen tcp: %w", err)
+	}
+
+	return NewListener(ln), nil
+}
+
+// NewListener starts accepting connections from ln in the background.
+func NewListener(ln net.Listener) *Listener {
+	l := &Listener{
+		ln:     ln,
+		conns:  make(chan srpc.ServerConn),
+		active: make(map[*serverSession]struct{}),
+	}
+	l.ctx, l.ctxCancel = context.WithCancel(context.Background())
+
+	l.wg.Add(1)
+	go l.serve()
+
+	return l
+}
+
+// Addr returns the address the listener is bound to.
+func (l *Listener) Addr() string {
+	return l.ln.Addr().String()
+}
+
+// Close closes the listener.
+// Any blocked Accept operations will be unblocked and return errors.
+// Accepted calls are served until they are closed, connections are closed
+// once they have no calls in progress. New calls on these connections are
+// rejected.
+// Close can be called multiple times.
+func (l *Listener) Close() (err error) {
+	l.closeOnce.Do(func() { err = l.close() })
+	return err
+}
+
+func (l *Listener) close() error {
+	l.ctxCancel()
+	err := l.ln.Close()
+
+	l.mu.Lock()
+	for sess := range l.active {
+		sess.drain()
+	}
+	l.mu.Unlock()
+
+	l.wg.Wait()
+	return err
+}
+
+// Accept waits and returns new connection to the listener.
+// If Listener got closed Accept must return [ErrListenerClosed],
+// including Accept calls that didn't returned yet.
+func (l *Listener) Accept() (srpc.ServerConn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.ctx.Done():
+		return nil, srpc.ErrListenerClosed
+	}
+}
+
+func (l *Listener) serve() {
+	defer l.wg.Done()
+
+	for {
+		nc, err := l.ln.Accept()
+		if err != nil {
+			// listener is closed or broken, unblock Accept calls
+			l.ctxCancel()
+			return
+		}
+
+		sess := &serverSession{
+			l: l, nc: nc,
+			fw:      newFrameWriter(nc),
+			streams: make(map[uint32]*serverConn),
+		}
+		if !l.track(sess) {
+			nc.Close()
+			return
+		}
+
+		// session outlives the listener until its calls are finished, so
+		// it is not waited on Close
+		go func() {
+			defer l.untrack(sess)
+			defer nc.Close()
+
+			l.handle(sess)
+		}()
+	}
+}
+
+func (l *Listener) track(sess *serverSession) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.ctx.Err() != nil {
+		return false
+	}
+	l.active[sess] = struct{}{}
+	return true
+}
+
+func (l *Listener) untrack(sess *serverSession) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.active, sess)
+}
+
+// handle reads the frames from the connection and dispatches them to the
+// streams. Every new stream is passed to Accept.
+func (l *Listener) handle(sess *serverSession) {
+	defer sess.closeStreams()
+
+	r := bufio.NewReader(sess.nc)
+
+	for {
+		typ, id, payload, err := readFrame(r)
+		if err != nil {
+			return
+		}
+
+		switch typ {
+		case frameRequest:
+			h, err := unmarshalHeader(payload)
+			if err != nil {
+				return
+			}
+			sess.open(id, h)
+		case frameData:
+			if conn := sess.stream(id); conn != nil {
+				if err := conn.body.write(payload); err != nil {
+					return
+				}
+			}
+		case frameEnd:
+			if conn := sess.stream(id); conn != nil {
+				conn.body.closeWithError(endError(payload))
+			}
+		case frameCancel:
+			if conn := sess.stream(id); conn != nil {
+				conn.cancel()
+			}
+		case frameWindow:
+			n, err := parseWindow(payload)
+			if err != nil {
+				return
+			}
+			if conn := sess.stream(id); conn != nil {
+				conn.sendWin.add(n)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// serverSession holds the streams of a single connection.
+type serverSession struct {
+	l  *Listener
+	nc net.Conn
+	fw *frameWriter
+
+	mu       sync.Mutex
+	streams  map[uint32]*serverConn
+	draining bool // listener is closed, no new streams are accepted
+}
+
+var errListenerClosed = srpc.Errorf(srpc.CodeUnavailable, "server is shutting down")
+
+// drain closes the connection once it has no streams in progress.
+func (s *serverSession) drain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.draining = true
+	if len(s.streams) == 0 {
+		s.nc.Close()
+	}
+}
+
+// reject replies to the new stream with err.
+func (s *serverSession) reject(id uint32, h header, err *srpc.Error) {
+	s.fw.writeFrame(frameResponse, id, marshalHeader(header{
+		ServiceMethod: h.ServiceMethod,
+		StatusCode:    srpc.StatusInternalError,
+		HasError:      true,
+		Error:         string(srpc.MarshalError(err)),
+	}))
+	s.fw.writeFrame(frameEnd, id, nil)
+}
+
+func (s *serverSession) open(id uint32, h header) {
+	body := newBuffer(func(n int) {
+		s.fw.writeFrame(frameWindow, id, windowPayload(n))
+	})
+	conn := &serverConn{
+		sess: s, id: id,
+		req: srpc.Request{
+			ServiceMethod: h.ServiceMethod,
+			Metadata:      h.Metadata,
+			Body:          body,
+		},
+		body:    body,
+		sendWin: newWindow(),
+	}
+	conn.ctx, conn.ctxCancel = context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	if s.draining {
+		s.mu.Unlock()
+		s.reject(id, h, errListenerClosed)
+		return
+	}
+	if old, ok := s.streams[id]; ok {
+		old.cancel()
+	}
+	s.streams[id] = conn
+	s.mu.Unlock()
+
+	// pass connection to Accept() without blocking other streams
+	go func() {
+		select {
+		case s.l.conns <- conn:
+		case <-s.l.ctx.Done():
+			s.reject(id, h, errListenerClosed)
+			conn.Close()
+		case <-conn.ctx.Done():
+			conn.Close()
+		}
+	}()
+}
+
+func (s *serverSession) stream(id uint32) *serverConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.streams[id]
+}
+
+func (s *serverSession) remove(conn *serverConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.streams[conn.id] == conn {
+		delete(s.streams, conn.id)
+	}
+
+	if s.draining && len(s.streams) == 0 {
+		s.nc.Close()
+	}
+}
+
+// closeStreams cancels all streams, once the connection is lost.
+func (s *serverSession) closeStreams() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.streams {
+		conn.ctxCancel()
+		conn.body.abort(io.ErrUnexpectedEOF)
+		conn.sendWin.close(io.ErrUnexpectedEOF)
+	}
+}
+
+type serverConn struct {
+	sess *serverSession
+	id   uint32
+
+	ctx       context.Context // cancelled when client abandons the call
+	ctxCancel context.CancelFunc
+
+	req     srpc.Request
+	body    *buffer
+	sendWin *window
+}
+
+func (c *serverConn) Request() srpc.Request {
+	return c.req
+}
+
+func (c *serverConn) Addr() string {
+	return c.sess.nc.RemoteAddr().String()
+}
+
+func (c *serverConn) Context() context.Context {
+	return c.ctx
+}
+
+func (c *serverConn) Reply(ctx context.Context, resp srpc.Response) error {
+	h := header{
+		ServiceMethod: resp.ServiceMethod,
+		Metadata:      resp.Metadata,
+		StatusCode:    resp.StatusCode,
+	}
+	body := resp.Body
+	if resp.Error != nil {
+		h.HasError, h.Error = true, string(srpc.MarshalError(resp.Error))
+		body = nil
+	}
+
+	if err := c.sess.fw.writeFrame(frameResponse, c.id, marshalHeader(h)); err != nil {
+		return fmt.Errorf("write response header: %w", err)
+	}
+
+	if err := c.sess.fw.writeBody(c.id, body, c.sendWin); err != nil {
+		return fmt.Errorf("write response body: %w", err)
+	}
+
+	return nil
+}
+
+// cancel is called when the client abandons the call.
+func (c *serverConn) cancel() {
+	c.ctxCancel()
+	c.body.abort(context.Canceled)
+	c.sendWin.close(context.Canceled)
+}
+
+// Close must be called after Send
+func (c *serverConn) Close() error {
+	c.ctxCancel()
+	c.body.abort(errStreamClosed)
+	c.sendWin.close(errStreamClosed)
+	c.sess.remove(c)
+	return nil
+}
+
+var errStreamClosed = errors.New("stream is closed")
@@ -0,0 +1,229 @@
+package tcptransport
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// frameWriter writes whole frames to the connection, so the frames of
+// different streams don't mix.
+type frameWriter struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+func newFrameWriter(nc net.Conn) *frameWriter {
+	return &frameWriter{w: bufio.NewWriter(nc)}
+}
+
+func (fw *frameWriter) writeFrame(typ frameType, id uint32, payload []byte) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if err := writeFrame(fw.w, typ, id, payload); err != nil {
+		return err
+	}
+
+	return fw.w.Flush()
+}
+
+// writeBody sends body as [frameData] frames, followed by [frameEnd].
+// If reading the body fails, the error is sent within [frameEnd], so the
+// other side knows the body is incomplete. Frames are sent as long as win
+// allows.
+func (fw *frameWriter) writeBody(id uint32, body io.Reader, win *window) error {
+	if body == nil {
+		return fw.writeFrame(frameEnd, id, nil)
+	}
+
+	buf := make([]byte, _dataChunkSize)
+	for {
+		n, err := body.Read(buf)
+		for p := buf[:n]; len(p) > 0; {
+			size, err := win.take(len(p))
+			if err != nil {
+				return err
+			}
+			if err := fw.writeFrame(frameData, id, p[:size]); err != nil {
+				return err
+			}
+			p = p[size:]
+		}
+		if errors.Is(err, io.EOF) {
+			return fw.writeFrame(frameEnd, id, nil)
+		}
+		if err != nil {
+			if err := fw.writeFrame(frameEnd, id, []byte(err.Error())); err != nil {
+				return err
+			}
+			return fmt.Errorf("read body: %w", err)
+		}
+	}
+}
+
+// window is the amount of data the stream is allowed to send.
+type window struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	size int
+	err  error // set once the stream can't send anymore
+}
+
+func newWindow() *window {
+	w := &window{size: _initialWindow}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// take waits until the window is open and takes up to n bytes from it.
+func (w *window) take(n int) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.size == 0 && w.err == nil {
+		w.cond.Wait()
+	}
+
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n = min(n, w.size)
+	w.size -= n
+	return n, nil
+}
+
+func (w *window) add(n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.size += n
+	w.cond.Broadcast()
+}
+
+// close makes the blocked and subsequent takes fail with err.
+func (w *window) close(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err == nil {
+		w.err = err
+	}
+	w.cond.Broadcast()
+}
+
+// errWindowExceeded is the protocol error of the peer, which sent more data
+// than the receive window of the stream allows.
+var errWindowExceeded = errors.New("data exceeds receive window")
+
+// buffer holds the received body of a stream until it is read, so the
+// connection reader never waits for a slow stream. The amount of buffered
+// data is limited by the sender's [window], which is extended with ack as
+// the data is read. The buffer tracks the window granted to the sender, so
+// the sender ignoring it can't make it grow without limit.
+type buffer struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	data    []byte
+	err     error // returned once data is read
+	credit  int   // data the sender is allowed to send
+	unacked int
+	ack     func(n int)
+}
+
+func newBuffer(ack func(n int)) *buffer {
+	b := &buffer{credit: _initialWindow, ack: ack}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *buffer) Read(p []byte) (int, error) {
+	b.mu.Lock()
+
+	for len(b.data) == 0 && b.err == nil {
+		b.cond.Wait()
+	}
+
+	if len(b.data) == 0 {
+		err := b.err
+		b.mu.Unlock()
+		return 0, err
+	}
+
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+
+	// ack in batches, not to flood the connection with small frames
+	var ack int
+	b.unacked += n
+	if b.unacked >= _initialWindow/4 {
+		ack, b.unacked = b.unacked, 0
+		b.credit += ack
+	}
+	b.mu.Unlock()
+
+	if ack > 0 {
+		b.ack(ack)
+	}
+
+	return n, nil
+}
+
+// write appends p to the buffer, unless it is already closed. It fails with
+// [errWindowExceeded] if p doesn't fit into the window granted to the
+// sender.
+func (b *buffer) write(p []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(p) > b.credit {
+		return errWindowExceeded
+	}
+	b.credit -= len(p)
+
+	if b.err != nil {
+		return nil
+	}
+
+	b.data = append(b.data, p...)
+	b.cond.Broadcast()
+	return nil
+}
+
+// closeWithError makes Read return err once the data is read. If the buffer
+// is already closed it does nothing.
+func (b *buffer) closeWithError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return
+	}
+
+	b.err = err
+	b.cond.Broadcast()
+}
+
+// abort drops the unread data and makes Read return err right away.
+func (b *buffer) abort(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = nil
+	if b.err == nil || b.err == io.EOF {
+		b.err = err
+	}
+	b.cond.Broadcast()
+}
+
+// endError converts the payload of [frameEnd] to the error for the reader.
+func endError(payload []byte) error {
+	if len(payload) > 0 {
+		return fmt.Errorf("remote: %s", payload)
+	}
+	return io.EOF
+}
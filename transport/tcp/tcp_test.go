@@ -0,0 +1,540 @@
+package tcptransport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tymbaca/srpc"
+	"github.com/tymbaca/srpc/codec"
+	"github.com/tymbaca/srpc/internal/srpctest"
+	"github.com/tymbaca/srpc/logger"
+	"github.com/tymbaca/srpc/transport/testdata"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestTcpTransport(t *testing.T) {
+	ctx := t.Context()
+
+	l, err := Listen("127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := testdata.NewTestServiceServer(srpc.NewServer(codec.JSON))
+	defer server.Close()
+	go server.Start(ctx, l)
+
+	client := testdata.NewTestServiceClient(srpc.NewClient(l.Addr(), codec.JSON, NewClientConnector()))
+	{
+		resp, err := client.Add(ctx, testdata.AddReq{A: 10, B: 15})
+		require.NoError(t, err)
+		require.Equal(t, 25, resp.Result)
+	}
+	{
+		resp, err := client.Divide(ctx, testdata.DivideReq{A: 10, B: 2})
+		require.NoError(t, err)
+		require.Equal(t, 5, resp.Result)
+	}
+	{
+		_, err := client.Divide(ctx, testdata.DivideReq{A: 10, B: 0})
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(err))
+		detail, ok := srpc.ErrorDetail[testdata.DivideByZero](err)
+		require.True(t, ok)
+		require.Equal(t, 10, detail.A)
+	}
+	{
+		var got []int
+		for resp, err := range client.Count(ctx, testdata.CountReq{N: 5}) {
+			require.NoError(t, err)
+			got = append(got, resp.I)
+		}
+		require.Equal(t, []int{0, 1, 2, 3, 4}, got)
+	}
+	{
+		// breaking the loop abandons the stream
+		for resp, err := range client.Count(ctx, testdata.CountReq{N: 1000}) {
+			require.NoError(t, err)
+			if resp.I == 2 {
+				break
+			}
+		}
+	}
+	{
+		var errs []error
+		for _, err := range client.Count(ctx, testdata.CountReq{N: -1}) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		require.ErrorIs(t, errs[0], srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(errs[0]))
+	}
+	{
+		call := client.Sum(ctx)
+		for i := range 5 {
+			require.NoError(t, call.Send(testdata.SumReq{N: i}))
+		}
+		resp, err := call.CloseAndRecv()
+		require.NoError(t, err)
+		require.Equal(t, 10, resp.Sum)
+	}
+	{
+		call := client.Sum(ctx)
+		require.NoError(t, call.Send(testdata.SumReq{N: -1}))
+		_, err := call.CloseAndRecv()
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeInvalidArgument, srpc.CodeOf(err))
+	}
+	{
+		call := client.Echo(ctx)
+		for _, text := range []string{"a", "b", "c"} {
+			require.NoError(t, call.Send(testdata.EchoMsg{Text: text}))
+			msg, err := call.Recv()
+			require.NoError(t, err)
+			require.Equal(t, text, msg.Text)
+		}
+		require.NoError(t, call.CloseSend())
+		_, err := call.Recv()
+		require.ErrorIs(t, err, io.EOF)
+	}
+	{
+		// server finishes the call before the client
+		call := client.Echo(ctx)
+		require.NoError(t, call.Send(testdata.EchoMsg{Text: "stop"}))
+		_, err := call.Recv()
+		require.ErrorIs(t, err, srpc.ErrServiceError)
+		require.Equal(t, srpc.CodeAborted, srpc.CodeOf(err))
+		require.ErrorIs(t, call.Send(testdata.EchoMsg{Text: "a"}), io.EOF)
+	}
+	{
+		err := client.Call(ctx, "TestService.Unknown", testdata.AddReq{}, &testdata.AddResp{})
+		require.ErrorIs(t, err, srpc.ErrTransportError)
+		require.Equal(t, srpc.CodeUnimplemented, srpc.CodeOf(err))
+	}
+}
+
+func TestTcpTransportMetadata(t *testing.T) {
+	ctx := t.Context()
+
+	l, err := Listen("127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := testdata.NewTestServiceServer(srpc.NewServer(codec.JSON))
+	defer server.Close()
+	go server.Start(ctx, l)
+
+	client := srpc.NewClient(l.Addr(), codec.JSON, NewClientConnector())
+
+	// unknown method still echoes service method and returns metadata
+	var header srpc.Metadata
+	err = client.Call(ctx, "TestService.Unknown", testdata.AddReq{}, &testdata.AddResp{}, srpc.Header(&header))
+	require.ErrorIs(t, err, srpc.ErrTransportError)
+	require.NotNil(t, header)
+}
+
+func TestTcpTransportStress(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("single client", func(t *testing.T) {
+		l, err := Listen("127.0.0.1:0")
+		require.NoError(t, err)
+		server := testdata.NewTestServiceServer(srpc.NewServer(codec.JSON, srpc.WithLogger(logger.DefaulSLogger{})))
+		defer server.Close()
+		go server.Start(ctx, l)
+
+		client := testdata.NewTestServiceClient(srpc.NewClient(l.Addr(), codec.JSON, NewClientConnector()))
+		resp, err := client.Add(ctx, testdata.AddReq{A: 10, B: 15})
+		require.NoError(t, err)
+		require.Equal(t, 25, resp.Result)
+	})
+
+	t.Run("multiple clients parallel each multiple calls", func(t *testing.T) {
+		l, err := Listen("127.0.0.1:0")
+		require.NoError(t, err)
+		server := testdata.NewTestServiceServer(srpc.NewServer(codec.JSON, srpc.WithLogger(logger.DefaulSLogger{})))
+		defer server.Close()
+		go server.Start(ctx, l)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client := testdata.NewTestServiceClient(srpc.NewClient(l.Addr(), codec.JSON, NewClientConnector()))
+				for range 10 {
+					req := testdata.AddReq{A: rand.Int(), B: rand.Int()}
+					resp, err := client.Add(ctx, req)
+					require.NoError(t, err)
+					require.Equal(t, req.A+req.B, resp.Result)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("server closes during calls", func(t *testing.T) {
+		l, err := Listen("127.0.0.1:0")
+		require.NoError(t, err)
+		server := testdata.NewTestServiceServer(srpc.NewServer(codec.JSON, srpc.WithLogger(logger.DefaulSLogger{})))
+		defer server.Close()
+		go server.Start(ctx, l)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client := testdata.NewTestServiceClient(srpc.NewClient(l.Addr(), codec.JSON, NewClientConnector()))
+				for {
+					req := testdata.AddReq{A: rand.Int(), B: rand.Int()}
+					if _, err := client.Add(ctx, req); err != nil {
+						return
+					}
+				}
+			}()
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		server.Close()
+		wg.Wait()
+	})
+}
+
+func BenchmarkTcpTransportStress(b *testing.B) {
+	ctx := b.Context()
+
+	l, err := Listen("127.0.0.1:0")
+	require.NoError(b, err)
+	server := testdata.NewTestServiceServer(srpc.NewServer(codec.JSON, srpc.WithLogger(logger.DefaulSLogger{})))
+	defer server.Close()
+	go server.Start(ctx, l)
+
+	client := testdata.NewTestServiceClient(srpc.NewClient(l.Addr(), codec.JSON, NewClientConnector()))
+
+	for b.Loop() {
+		req := testdata.AddReq{A: rand.Int(), B: rand.Int()}
+		resp, err := client.Add(ctx, req)
+		_, _ = resp, err
+	}
+}
+
+func BenchmarkTcpTransportCodecs(b *testing.B) {
+	for _, bc := range srpctest.Codecs {
+		b.Run(bc.Name, func(b *testing.B) {
+			ctx := b.Context()
+
+			l, err := Listen("127.0.0.1:0")
+			require.NoError(b, err)
+			server := testdata.NewTestServiceServer(srpc.NewServer(bc.Codec, srpc.WithLogger(logger.DefaulSLogger{})))
+			defer server.Close()
+			go server.Start(ctx, l)
+
+			client := testdata.NewTestServiceClient(srpc.NewClient(l.Addr(), bc.Codec, NewClientConnector()))
+
+			b.ReportAllocs()
+			for b.Loop() {
+				req := testdata.AddReq{A: rand.Int(), B: rand.Int()}
+				resp, err := client.Add(ctx, req)
+				_, _ = resp, err
+			}
+		})
+	}
+}
+
+type slowService struct {
+	cancelled       chan struct{}
+	streamCancelled chan struct{}
+	resume          chan struct{}
+}
+
+func (s *slowService) Sleep(ctx context.Context, d time.Duration) (time.Duration, error) {
+	select {
+	case <-time.After(d):
+		return d, nil
+	case <-ctx.Done():
+		close(s.cancelled)
+		return 0, ctx.Err()
+	}
+}
+
+// Stall starts reading the stream once resume is closed.
+func (s *slowService) Stall(ctx context.Context, stream *srpc.ClientStream[[]byte]) (int, error) {
+	select {
+	case <-s.resume:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	var total int
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return total, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += len(chunk)
+	}
+}
+
+// Wait echoes the first message and waits until the call is cancelled.
+func (s *slowService) Wait(ctx context.Context, stream *srpc.BidiStream[int, int]) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(msg); err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	close(s.streamCancelled)
+	return ctx.Err()
+}
+
+func TestTcpMuxTransport(t *testing.T) {
+	ctx := t.Context()
+
+	l, err := Listen("127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := srpc.NewServer(codec.JSON)
+	svc := &slowService{
+		cancelled:       make(chan struct{}),
+		streamCancelled: make(chan struct{}),
+		resume:          make(chan struct{}),
+	}
+	srpc.RegisterWithName(s, svc, "Slow")
+	server := testdata.NewTestServiceServer(s)
+	defer server.Close()
+	go server.Start(ctx, l)
+
+	connector := NewMuxConnector()
+	defer connector.Close()
+	client := srpc.NewClient(l.Addr(), codec.JSON, connector)
+	testClient := testdata.NewTestServiceClient(client)
+
+	activeConns := func() int {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return len(l.active)
+	}
+
+	t.Run("calls", func(t *testing.T) {
+		resp, err := testClient.Add(ctx, testdata.AddReq{A: 10, B: 15})
+		require.NoError(t, err)
+		require.Equal(t, 25, resp.Result)
+
+		_, err = testClient.Divide(ctx, testdata.DivideReq{A: 10, B: 0})
+		require.Error(t, err)
+	})
+
+	t.Run("out of order responses", func(t *testing.T) {
+		var (
+			wg    sync.WaitGroup
+			mu    sync.Mutex
+			order []time.Duration
+		)
+		for _, d := range []time.Duration{200 * time.Millisecond, 10 * time.Millisecond} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var resp time.Duration
+				require.NoError(t, client.Call(ctx, "Slow.Sleep", d, &resp))
+				mu.Lock()
+				order = append(order, resp)
+				mu.Unlock()
+			}()
+			time.Sleep(5 * time.Millisecond)
+		}
+		wg.Wait()
+
+		require.Equal(t, []time.Duration{10 * time.Millisecond, 200 * time.Millisecond}, order)
+		require.Equal(t, 1, activeConns())
+	})
+
+	t.Run("cancel single call", func(t *testing.T) {
+		callCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		var resp time.Duration
+		err := client.Call(callCtx, "Slow.Sleep", time.Minute, &resp)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		select {
+		case <-svc.cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("handler context was not cancelled")
+		}
+
+		// connection survives
+		addResp, err := testClient.Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.NoError(t, err)
+		require.Equal(t, 3, addResp.Result)
+		require.Equal(t, 1, activeConns())
+	})
+
+	t.Run("flow control", func(t *testing.T) {
+		const chunks = 64
+		chunk := make([]byte, 32<<10)
+
+		call := srpc.CallClientStream[[]byte, int](ctx, client, "Slow.Stall")
+		var sent atomic.Int64
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range chunks {
+				if err := call.Send(chunk); err != nil {
+					return
+				}
+				sent.Add(int64(len(chunk)))
+			}
+		}()
+
+		// sender is blocked by the window, other calls are not
+		time.Sleep(100 * time.Millisecond)
+		addResp, err := testClient.Add(ctx, testdata.AddReq{A: 1, B: 2})
+		require.NoError(t, err)
+		require.Equal(t, 3, addResp.Result)
+		require.Less(t, sent.Load(), int64(1<<20))
+
+		close(svc.resume)
+		<-done
+		total, err := call.CloseAndRecv()
+		require.NoError(t, err)
+		require.Equal(t, chunks*len(chunk), total)
+	})
+
+	t.Run("cancel stream", func(t *testing.T) {
+		callCtx, cancel := context.WithCancel(ctx)
+		call := srpc.CallBidiStream[int, int](callCtx, client, "Slow.Wait")
+		require.NoError(t, call.Send(1))
+		msg, err := call.Recv()
+		require.NoError(t, err)
+		require.Equal(t, 1, msg)
+		cancel()
+
+		select {
+		case <-svc.streamCancelled:
+		case <-time.After(time.Second):
+			t.Fatal("handler context was not cancelled")
+		}
+
+		_, err = call.Recv()
+		require.Error(t, err)
+		require.Equal(t, 1, activeConns())
+	})
+
+	t.Run("parallel calls", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 10 {
+					req := testdata.AddReq{A: rand.Int(), B: rand.Int()}
+					resp, err := testClient.Add(ctx, req)
+					require.NoError(t, err)
+					require.Equal(t, req.A+req.B, resp.Result)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestTcpTransportShutdown(t *testing.T) {
+	ctx := t.Context()
+
+	l, err := Listen("127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := srpc.NewServer(codec.JSON)
+	srpc.RegisterWithName(s, &slowService{cancelled: make(chan struct{})}, "Slow")
+	server := testdata.NewTestServiceServer(s)
+	defer server.Close()
+	go server.Start(ctx, l)
+
+	connector := NewMuxConnector()
+	defer connector.Close()
+	client := srpc.NewClient(l.Addr(), codec.JSON, connector)
+	defer client.Close()
+
+	callErr := make(chan error, 1)
+	go func() {
+		var resp time.Duration
+		callErr <- client.Call(ctx, "Slow.Sleep", 100*time.Millisecond, &resp)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- server.Shutdown(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+
+	// the connection is kept for the call in progress, but new calls are
+	// rejected
+	_, err = testdata.NewTestServiceClient(client).Add(ctx, testdata.AddReq{A: 1, B: 2})
+	require.Equal(t, srpc.CodeUnavailable, srpc.CodeOf(err))
+
+	require.NoError(t, <-callErr)
+	require.NoError(t, <-shutdownErr)
+}
+
+func TestTcpTransportWindowExceeded(t *testing.T) {
+	ctx := t.Context()
+
+	l, err := Listen("127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := srpc.NewServer(codec.JSON)
+	srpc.RegisterWithName(server, &slowService{resume: make(chan struct{})}, "Slow")
+	defer server.Close()
+	go server.Start(ctx, l)
+
+	nc, err := net.Dial("tcp", l.Addr())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	// the handler doesn't read the body, so the client has no more than the
+	// initial window to send
+	require.NoError(t, writeFrame(nc, frameRequest, 1, marshalHeader(header{ServiceMethod: "Slow.Stall"})))
+	require.NoError(t, writeFrame(nc, frameData, 1, make([]byte, _initialWindow)))
+	require.NoError(t, writeFrame(nc, frameData, 1, []byte{0}))
+
+	// the server drops the connection of the client ignoring the window
+	nc.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.Copy(io.Discard, nc)
+	var netErr net.Error
+	require.False(t, errors.As(err, &netErr) && netErr.Timeout(), "connection is not closed")
+}
+
+func BenchmarkTcpMuxTransportStress(b *testing.B) {
+	ctx := b.Context()
+
+	l, err := Listen("127.0.0.1:0")
+	require.NoError(b, err)
+	server := testdata.NewTestServiceServer(srpc.NewServer(codec.JSON, srpc.WithLogger(logger.DefaulSLogger{})))
+	defer server.Close()
+	go server.Start(ctx, l)
+
+	connector := NewMuxConnector()
+	defer connector.Close()
+	client := testdata.NewTestServiceClient(srpc.NewClient(l.Addr(), codec.JSON, connector))
+
+	for b.Loop() {
+		req := testdata.AddReq{A: rand.Int(), B: rand.Int()}
+		resp, err := client.Add(ctx, req)
+		_, _ = resp, err
+	}
+}
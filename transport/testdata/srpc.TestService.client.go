@@ -5,6 +5,7 @@ package testdata
 import (
 	"context"
 	"github.com/tymbaca/srpc"
+	"iter"
 )
 
 func NewTestServiceClient(client *srpc.Client) *TestServiceClient {
@@ -15,12 +16,24 @@ type TestServiceClient struct {
 	*srpc.Client
 }
 
-func (c *TestServiceClient) Add(ctx context.Context, req AddReq) (resp AddResp, err error) {
-	err = c.Client.Call(ctx, "TestService.Add", req, &resp)
+func (c *TestServiceClient) Add(ctx context.Context, req AddReq, opts ...srpc.CallOption) (resp AddResp, err error) {
+	err = c.Client.Call(ctx, "TestService.Add", req, &resp, opts...)
 	return resp, err
 }
 
-func (c *TestServiceClient) Divide(ctx context.Context, req DivideReq) (resp DivideResp, err error) {
-	err = c.Client.Call(ctx, "TestService.Divide", req, &resp)
+func (c *TestServiceClient) Count(ctx context.Context, req CountReq, opts ...srpc.CallOption) iter.Seq2[CountResp, error] {
+	return srpc.CallServerStream[CountResp](ctx, c.Client, "TestService.Count", req, opts...)
+}
+
+func (c *TestServiceClient) Divide(ctx context.Context, req DivideReq, opts ...srpc.CallOption) (resp DivideResp, err error) {
+	err = c.Client.Call(ctx, "TestService.Divide", req, &resp, opts...)
 	return resp, err
 }
+
+func (c *TestServiceClient) Echo(ctx context.Context, opts ...srpc.CallOption) *srpc.BidiStreamCall[EchoMsg, EchoMsg] {
+	return srpc.CallBidiStream[EchoMsg, EchoMsg](ctx, c.Client, "TestService.Echo", opts...)
+}
+
+func (c *TestServiceClient) Sum(ctx context.Context, opts ...srpc.CallOption) *srpc.ClientStreamCall[SumReq, SumResp] {
+	return srpc.CallClientStream[SumReq, SumResp](ctx, c.Client, "TestService.Sum", opts...)
+}
@@ -5,6 +5,7 @@ package testdata
 import (
 	"context"
 	"errors"
+	"io"
 
 	"github.com/tymbaca/srpc"
 )
@@ -29,8 +30,60 @@ func (s *TestServiceServer) Add(ctx context.Context, req AddReq) (AddResp, error
 
 func (s *TestServiceServer) Divide(ctx context.Context, req DivideReq) (DivideResp, error) {
 	if req.B == 0 {
-		return DivideResp{}, errors.New("can't divide to 0")
+		return DivideResp{}, srpc.NewError(srpc.CodeInvalidArgument, "can't divide to 0", DivideByZero{A: req.A})
 	}
 
 	return DivideResp{req.A / req.B}, nil
 }
+
+func (s *TestServiceServer) Count(ctx context.Context, req CountReq, stream *srpc.ServerStream[CountResp]) error {
+	if req.N < 0 {
+		return srpc.Errorf(srpc.CodeInvalidArgument, "negative count: %d", req.N)
+	}
+
+	for i := range req.N {
+		if err := stream.Send(CountResp{I: i}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *TestServiceServer) Sum(ctx context.Context, stream *srpc.ClientStream[SumReq]) (SumResp, error) {
+	var sum int
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return SumResp{Sum: sum}, nil
+		}
+		if err != nil {
+			return SumResp{}, err
+		}
+
+		if req.N < 0 {
+			return SumResp{}, srpc.Errorf(srpc.CodeInvalidArgument, "negative number: %d", req.N)
+		}
+		sum += req.N
+	}
+}
+
+func (s *TestServiceServer) Echo(ctx context.Context, stream *srpc.BidiStream[EchoMsg, EchoMsg]) error {
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Text == "stop" {
+			return srpc.Errorf(srpc.CodeAborted, "stopped")
+		}
+
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package testdata
 
-import "context"
+import (
+	"context"
+
+	"github.com/tymbaca/srpc"
+)
 
 type (
 	AddReq struct {
@@ -18,10 +22,46 @@ type (
 	DivideResp struct {
 		Result int
 	}
+	// DivideByZero is the detail of the error, returned by Divide.
+	DivideByZero struct {
+		A int
+	}
 )
 
+type (
+	CountReq struct {
+		N int
+	}
+	CountResp struct {
+		I int
+	}
+)
+
+type (
+	SumReq struct {
+		N int
+	}
+	SumResp struct {
+		Sum int
+	}
+)
+
+type EchoMsg struct {
+	Text string
+}
+
+func init() {
+	srpc.RegisterErrorDetail[DivideByZero]()
+}
+
 //go:generate srpc-gen --target=TestService
 type TestService interface {
 	Add(ctx context.Context, req AddReq) (AddResp, error)
 	Divide(ctx context.Context, req DivideReq) (DivideResp, error)
+	// Count streams numbers from 0 to N-1.
+	Count(ctx context.Context, req CountReq, stream *srpc.ServerStream[CountResp]) error
+	// Sum sums all received numbers.
+	Sum(ctx context.Context, stream *srpc.ClientStream[SumReq]) (SumResp, error)
+	// Echo sends back every received message, until it gets "stop".
+	Echo(ctx context.Context, stream *srpc.BidiStream[EchoMsg, EchoMsg]) error
 }
@@ -1,5 +1,10 @@
 package srpc
 
+import (
+	"io"
+	"sync"
+)
+
 func assert(cond bool) {
 	if !cond {
 		panic("assertion failure")
@@ -13,3 +18,33 @@ func tern[T any](cond bool, a, b T) T {
 		return b
 	}
 }
+
+// watchedBody closes done once the body is read completely or closed.
+type watchedBody struct {
+	io.Reader
+	done chan struct{}
+	once sync.Once
+}
+
+func newWatchedBody(r io.Reader) *watchedBody {
+	return &watchedBody{Reader: r, done: make(chan struct{})}
+}
+
+func (b *watchedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err != nil {
+		b.once.Do(func() { close(b.done) })
+	}
+
+	return n, err
+}
+
+// Close closes the underlying body, if it is [io.Closer].
+func (b *watchedBody) Close() error {
+	b.once.Do(func() { close(b.done) })
+	if c, ok := b.Reader.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}